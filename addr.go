@@ -0,0 +1,187 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/peer"
+)
+
+const observedAddrQuorum = 3
+const observedAddrCap = 16
+
+// SetListenAddr makes the node bind to addr instead of its NodeId, for nodes
+// that are dialed through an address they do not own, e.g. behind NAT or
+// inside a container. It must be called before Listen or Start.
+func (node *Node) SetListenAddr(addr string) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.listenAddr = addr
+}
+
+// SetUseObservedAddr makes the node advertise the address its peers see it
+// at, once enough of them agree on it.
+func (node *Node) SetUseObservedAddr(enabled bool) {
+	node.observed.lock.Lock()
+	defer node.observed.lock.Unlock()
+	node.observed.enabled = enabled
+}
+
+// Addr returns the address the node is bound to, or nil before Listen or
+// Start.
+func (node *Node) Addr() net.Addr {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.addr
+}
+
+func (node *Node) bind() (net.Listener, error) {
+	node.lock.Lock()
+	addr := node.listenAddr
+	if addr == "" {
+		addr = node.nodeId.String()
+	}
+	node.lock.Unlock()
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("[gossip] Cannot listen on %s: %s", addr, err.Error()))
+	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.addr = lis.Addr()
+	if nodeId, ok := resolveNodeId(node.nodeId, lis.Addr()); ok {
+		node.setNodeId(nodeId)
+	}
+	return lis, nil
+}
+
+// resolveNodeId fills in the port, and the host if missing, of a NodeId
+// that asked for an ephemeral port.
+func resolveNodeId(nodeId NodeId, bound net.Addr) (NodeId, bool) {
+	host, port, err := net.SplitHostPort(nodeId.String())
+	if err != nil || port != "0" {
+		return nodeId, false
+	}
+	boundHost, boundPort, err := net.SplitHostPort(bound.String())
+	if err != nil {
+		return nodeId, false
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if ip := net.ParseIP(boundHost); ip != nil && !ip.IsUnspecified() {
+			host = boundHost
+		}
+	}
+	return NewNodeId(net.JoinHostPort(host, boundPort)), true
+}
+
+// setNodeId must be called with node.lock held.
+func (node *Node) setNodeId(nodeId NodeId) {
+	node.nodeId = nodeId
+	node.neighbors.AddBlackList(nodeId)
+}
+
+// observedAddr is the address a caller advertising nodeId is seen at: the
+// host it connected from and the port it listens on.
+func observedAddr(ctx context.Context, nodeId NodeId) string {
+	tcpAddr, ok := callerAddr(ctx)
+	if !ok {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(nodeId.String())
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(tcpAddr.IP.String(), port)
+}
+
+// caller returns whom to hold to account for a call claiming to come from
+// claimed: claimed itself if it is on the host the call came from, or else
+// the address the call came from, so that nobody gets another node scored or
+// banned by passing for it. Calls that did not come over the network are
+// taken at their word.
+func caller(ctx context.Context, claimed NodeId) NodeId {
+	tcpAddr, ok := callerAddr(ctx)
+	if !ok {
+		return claimed
+	}
+	host, _, err := net.SplitHostPort(claimed.String())
+	if ip := net.ParseIP(host); err == nil && ip != nil && ip.Equal(tcpAddr.IP) {
+		return claimed
+	}
+	return NewNodeId(tcpAddr.String())
+}
+
+// connection returns the connection a call came over, which unlike the
+// sender a message claims cannot be changed with every message. Calls that
+// did not come over the network are keyed on claimed.
+func connection(ctx context.Context, claimed NodeId) NodeId {
+	tcpAddr, ok := callerAddr(ctx)
+	if !ok {
+		return claimed
+	}
+	return NewNodeId(tcpAddr.String())
+}
+
+func callerAddr(ctx context.Context) (*net.TCPAddr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	tcpAddr, ok := p.Addr.(*net.TCPAddr)
+	return tcpAddr, ok
+}
+
+type observedAddrs struct {
+	enabled bool
+	votes   map[NodeId]map[NodeId]bool
+	lock    *sync.Mutex
+}
+
+func newObservedAddrs() *observedAddrs {
+	return &observedAddrs{
+		votes: make(map[NodeId]map[NodeId]bool),
+		lock:  &sync.Mutex{},
+	}
+}
+
+// vote records that reporter sees the node at observed, and reports whether
+// enough distinct peers agree to switch to it.
+func (oa *observedAddrs) vote(reporter NodeId, observed NodeId) bool {
+	oa.lock.Lock()
+	defer oa.lock.Unlock()
+	if !oa.enabled {
+		return false
+	}
+	if _, ok := oa.votes[observed]; !ok {
+		if len(oa.votes) >= observedAddrCap {
+			oa.votes = make(map[NodeId]map[NodeId]bool)
+		}
+		oa.votes[observed] = make(map[NodeId]bool)
+	}
+	oa.votes[observed][reporter] = true
+	if len(oa.votes[observed]) < observedAddrQuorum {
+		return false
+	}
+	oa.votes = make(map[NodeId]map[NodeId]bool)
+	return true
+}
+
+func (node *Node) observe(reporter NodeId, observed NodeId) {
+	if observed == "" || observed == node.NodeId() {
+		return
+	}
+	if !node.observed.vote(reporter, observed) {
+		return
+	}
+	node.lock.Lock()
+	old := node.nodeId
+	node.setNodeId(observed)
+	node.lock.Unlock()
+	log.Printf("[gossip] node %s is now advertised as %s", old.String(), observed.String())
+}
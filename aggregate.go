@@ -0,0 +1,332 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const FeatureAggregation = "aggregation"
+
+const aggregateEpoch = time.Minute
+const aggregateRoundInterval = time.Second
+const aggregateTimeout = 5 * time.Second
+
+// an aggregate has converged once that many exchanges in a row changed it
+// by less than aggregateTolerance
+const aggregateStableRounds = 3
+const aggregateTolerance = 1e-3
+
+// AggregateValue returns the value the node contributes to an aggregate. It
+// is called every round, and the node contributes the first value it
+// returns in an epoch.
+type AggregateValue func() float64
+
+type AggregateResult struct {
+	Average float64
+	Min     float64
+	Max     float64
+	// Sum and Count scale the average by the estimated size of the network,
+	// so they are only as accurate as EstimatedSize.
+	Sum   float64
+	Count int
+	// Converged reports whether the estimate stopped changing.
+	Converged bool
+}
+
+// Contribute makes the node contribute value to the aggregate called name.
+// A nil value stops contributing from the next epoch on.
+func (node *Node) Contribute(name string, value AggregateValue) {
+	node.aggregates.lock.Lock()
+	defer node.aggregates.lock.Unlock()
+	if value == nil {
+		delete(node.aggregates.values, name)
+		return
+	}
+	node.aggregates.values[name] = value
+}
+
+// Aggregate returns the estimate of the aggregate called name over the
+// nodes that contribute to it. Nodes estimate it together with push-pull
+// averaging: neighbors keep exchanging their estimates and both adopt the
+// mean. The estimate starts over every epoch, so that it follows changes of
+// the values and of the network. While the estimate of an epoch has not
+// converged, that of the last one is returned if it had. It reports false
+// if the node did not learn of any contribution yet.
+func (node *Node) Aggregate(name string) (AggregateResult, bool) {
+	return node.aggregates.result(name, node.EstimatedSize())
+}
+
+func (node *Node) ExchangeAggregates(ctx context.Context, req *AggregateExchange) (*AggregateExchange, error) {
+	if req.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	epoch, states, err := node.aggregates.exchange(req.Epoch, req.States)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "[From %s] %s", node.NodeId().String(), err.Error())
+	}
+	return &AggregateExchange{Topic: node.topic, Epoch: epoch, States: states}, nil
+}
+
+// exchangeAggregates averages the aggregates with a random neighbor every
+// round, until the node is closed.
+func (node *Node) exchangeAggregates() {
+	for node.sleep(aggregateRoundInterval) {
+		node.aggregates.refresh()
+		nodeIds := node.neighbors.SampleNodeIdWhere(1, func(nodeId NodeId, info *PeerInfo) bool {
+			return info != nil && info.Supports(FeatureAggregation)
+		})
+		if len(nodeIds) == 0 {
+			continue
+		}
+		conn, err := node.neighbors.GetConn(nodeIds[0])
+		if err != nil {
+			continue
+		}
+		epoch, states, ok := node.aggregates.begin()
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), aggregateTimeout)
+		res, err := NewGossipClient(conn).ExchangeAggregates(ctx, &AggregateExchange{Topic: node.topic, Epoch: epoch, States: states})
+		cancel()
+		if err != nil {
+			node.aggregates.finish(0, nil)
+			continue
+		}
+		node.aggregates.finish(res.Epoch, res.States)
+	}
+}
+
+type aggregateState struct {
+	sum    float64
+	weight float64
+	min    float64
+	max    float64
+	stable int
+}
+
+func newAggregateState() *aggregateState {
+	return &aggregateState{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// set adopts the averaged state and counts how long the estimate has been
+// stable.
+func (s *aggregateState) set(sum, weight, min, max float64) {
+	if approxEqual(s.sum, sum) && approxEqual(s.weight, weight) && s.min == min && s.max == max {
+		s.stable++
+	} else {
+		s.stable = 0
+	}
+	s.sum, s.weight, s.min, s.max = sum, weight, min, max
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= aggregateTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func (s *aggregateState) converged() bool {
+	return s.stable >= aggregateStableRounds
+}
+
+// aggregates holds the states of the current epoch. Epochs follow the
+// clock, so that nodes start over at about the same time. A node takes part
+// in one exchange at a time, since the mass of overlapping exchanges would
+// get lost.
+type aggregates struct {
+	values      map[string]AggregateValue
+	local       map[string]float64
+	epoch       uint64
+	states      map[string]*aggregateState
+	contributed map[string]bool
+	previous    map[string]*aggregateState
+	busy        bool
+	lock        *sync.Mutex
+}
+
+func newAggregates() *aggregates {
+	a := &aggregates{
+		values: make(map[string]AggregateValue),
+		local:  make(map[string]float64),
+		lock:   &sync.Mutex{},
+	}
+	a.rotate(currentAggregateEpoch())
+	return a
+}
+
+func currentAggregateEpoch() uint64 {
+	return uint64(time.Now().UnixNano() / int64(aggregateEpoch))
+}
+
+// refresh reads the values the node contributes, without holding the lock
+// while the callbacks run.
+func (a *aggregates) refresh() {
+	a.lock.Lock()
+	values := make(map[string]AggregateValue, len(a.values))
+	for name, value := range a.values {
+		values[name] = value
+	}
+	a.lock.Unlock()
+	local := make(map[string]float64, len(values))
+	for name, value := range values {
+		if v := value(); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			local[name] = v
+		}
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.local = local
+	a.update(0)
+	for name, value := range local {
+		a.contribute(name, value)
+	}
+}
+
+// rotate starts epoch with the values of the node. The caller holds the
+// lock.
+func (a *aggregates) rotate(epoch uint64) {
+	if epoch == a.epoch+1 {
+		a.previous = a.states
+	} else {
+		a.previous = nil
+	}
+	a.epoch = epoch
+	a.states = make(map[string]*aggregateState)
+	a.contributed = make(map[string]bool)
+	for name, value := range a.local {
+		a.contribute(name, value)
+	}
+}
+
+// contribute adds the value of the node to the aggregate once per epoch.
+// The caller holds the lock.
+func (a *aggregates) contribute(name string, value float64) {
+	if a.contributed[name] {
+		return
+	}
+	a.contributed[name] = true
+	s, ok := a.states[name]
+	if !ok {
+		s = newAggregateState()
+		a.states[name] = s
+	}
+	s.sum += value
+	s.weight++
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+	s.stable = 0
+}
+
+// update moves to the current epoch, or to the next one if a peer is in it
+// already. Peers further ahead are ignored, so that they cannot skip epochs.
+// The caller holds the lock.
+func (a *aggregates) update(epoch uint64) {
+	if current := currentAggregateEpoch(); current > epoch || epoch > current+1 {
+		epoch = current
+	}
+	if epoch > a.epoch {
+		a.rotate(epoch)
+	}
+}
+
+// begin starts an exchange and returns the states to send, unless the node
+// is in one already. Nodes that know of no aggregate leave it to the others
+// to start exchanges.
+func (a *aggregates) begin() (uint64, []*AggregateState, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.update(0)
+	if a.busy || len(a.states) == 0 {
+		return 0, nil, false
+	}
+	a.busy = true
+	states := make([]*AggregateState, 0, len(a.states))
+	for name, s := range a.states {
+		states = append(states, &AggregateState{Name: name, Sum: s.sum, Weight: s.weight, Min: s.min, Max: s.max})
+	}
+	return a.epoch, states, true
+}
+
+// finish ends an exchange and adopts the averaged states the peer sent
+// back.
+func (a *aggregates) finish(epoch uint64, states []*AggregateState) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.busy = false
+	if states == nil {
+		return
+	}
+	a.update(epoch)
+	if epoch != a.epoch {
+		return
+	}
+	for _, state := range states {
+		s, ok := a.states[state.Name]
+		if !ok {
+			s = newAggregateState()
+			a.states[state.Name] = s
+		}
+		s.set(state.Sum, state.Weight, state.Min, state.Max)
+	}
+}
+
+// exchange averages the states of a peer with those of the node, which
+// both adopt. Aggregates only one of them knows count as zero for the
+// other.
+func (a *aggregates) exchange(epoch uint64, states []*AggregateState) (uint64, []*AggregateState, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.busy {
+		return 0, nil, errors.New("[gossip] Exchanging aggregates already")
+	}
+	a.update(epoch)
+	if epoch != a.epoch {
+		return 0, nil, errors.New(fmt.Sprintf("[gossip] Unexpected aggregate epoch %d", epoch))
+	}
+	peer := make(map[string]*AggregateState, len(states))
+	for _, state := range states {
+		peer[state.Name] = state
+	}
+	for name := range peer {
+		if _, ok := a.states[name]; !ok {
+			a.states[name] = newAggregateState()
+		}
+	}
+	averaged := make([]*AggregateState, 0, len(a.states))
+	for name, s := range a.states {
+		other, ok := peer[name]
+		if !ok {
+			other = &AggregateState{Min: math.Inf(1), Max: math.Inf(-1)}
+		}
+		s.set((s.sum+other.Sum)/2, (s.weight+other.Weight)/2, math.Min(s.min, other.Min), math.Max(s.max, other.Max))
+		averaged = append(averaged, &AggregateState{Name: name, Sum: s.sum, Weight: s.weight, Min: s.min, Max: s.max})
+	}
+	return a.epoch, averaged, nil
+}
+
+func (a *aggregates) result(name string, size int) (AggregateResult, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.update(0)
+	s, ok := a.states[name]
+	if last, found := a.previous[name]; found && last.weight > 0 && last.converged() && (!ok || !s.converged()) {
+		s, ok = last, true
+	}
+	if !ok || s.weight <= 0 {
+		return AggregateResult{}, false
+	}
+	return AggregateResult{
+		Average:   s.sum / s.weight,
+		Min:       s.min,
+		Max:       s.max,
+		Sum:       s.sum * float64(size),
+		Count:     int(math.Floor(s.weight*float64(size) + 0.5)),
+		Converged: s.converged(),
+	}, true
+}
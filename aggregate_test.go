@@ -0,0 +1,88 @@
+package gossip
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAggregates(t *testing.T) {
+	nodes := make([]*aggregates, 50)
+	for i := range nodes {
+		nodes[i] = newAggregates()
+		// every other node contributes
+		if i%2 == 0 {
+			value := float64(i)
+			nodes[i].values["load"] = func() float64 { return value }
+		}
+		nodes[i].refresh()
+	}
+	for round := 0; round < 60; round++ {
+		for i := range nodes {
+			peer := nodes[rand.Intn(len(nodes))]
+			if peer == nodes[i] {
+				continue
+			}
+			epoch, states, ok := nodes[i].begin()
+			if !ok {
+				// only nodes that know no aggregate yet sit out
+				if len(nodes[i].states) > 0 {
+					t.Fatalf("node %d did not start an exchange", i)
+				}
+				continue
+			}
+			epoch, states, err := peer.exchange(epoch, states)
+			if err != nil {
+				states = nil
+			}
+			nodes[i].finish(epoch, states)
+		}
+	}
+	for i := range nodes {
+		result, ok := nodes[i].result("load", len(nodes))
+		if !ok || !result.Converged {
+			t.Fatalf("node %d did not converge: %v", i, result)
+		}
+		// contributions are 0, 2, ..., 48
+		if math.Abs(result.Average-24) > 0.1 || result.Min != 0 || result.Max != 48 || result.Count != 25 {
+			t.Fatalf("unexpected result %v", result)
+		}
+	}
+	if _, ok := nodes[0].result("unknown", len(nodes)); ok {
+		t.Error("unexpected result for an unknown aggregate")
+	}
+}
+
+func TestAggregatesBegin(t *testing.T) {
+	idle := newAggregates()
+	idle.refresh()
+	if _, _, ok := idle.begin(); ok {
+		t.Error("node without aggregates started an exchange")
+	}
+	a := newAggregates()
+	a.values["load"] = func() float64 { return 1 }
+	a.refresh()
+	if _, _, ok := a.begin(); !ok {
+		t.Fatal("node with an aggregate did not start an exchange")
+	}
+	if _, _, ok := a.begin(); ok {
+		t.Error("node started a second exchange while busy")
+	}
+	if _, _, err := a.exchange(a.epoch, nil); err == nil {
+		t.Error("busy node accepted an exchange")
+	}
+	a.finish(0, nil)
+	if _, _, ok := a.begin(); !ok {
+		t.Error("node did not start an exchange after finishing")
+	}
+
+	// epochs beyond the next are not adopted
+	epoch := a.epoch
+	a.finish(0, nil)
+	if _, _, err := a.exchange(epoch+2, nil); err == nil || a.epoch != epoch {
+		t.Errorf("adopted epoch %d from the future", a.epoch)
+	}
+	if _, _, err := a.exchange(epoch+1, nil); err != nil || a.epoch != epoch+1 {
+		t.Errorf("did not adopt the next epoch: %v", err)
+	}
+}
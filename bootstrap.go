@@ -0,0 +1,140 @@
+package gossip
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+const defaultMinPeers = 1
+const eventBufferCap = 64
+const rebootstrapBaseDelay = 5 * time.Second
+const rebootstrapMaxDelay = 5 * time.Minute
+
+type EventType int
+
+const (
+	EventIsolated EventType = iota
+	EventRecovered
+	EventPeerMisconfigured
+	EventPeerBanned
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventIsolated:
+		return "isolated"
+	case EventRecovered:
+		return "recovered"
+	case EventPeerMisconfigured:
+		return "peer misconfigured"
+	case EventPeerBanned:
+		return "peer banned"
+	default:
+		return "unknown"
+	}
+}
+
+type Event struct {
+	Type   EventType
+	NodeId NodeId
+	Peers  int
+	Time   time.Time
+}
+
+// GetEventChan returns the channel on which the node reports changes of its
+// own state. Events are dropped if nobody reads them.
+func (node *Node) GetEventChan() chan Event {
+	return node.eventChan
+}
+
+func (node *Node) emit(eventType EventType, peers int) {
+	node.sendEvent(Event{Type: eventType, Peers: peers, Time: time.Now()})
+}
+
+func (node *Node) emitPeer(eventType EventType, nodeId NodeId) {
+	node.sendEvent(Event{Type: eventType, NodeId: nodeId, Time: time.Now()})
+}
+
+func (node *Node) sendEvent(event Event) {
+	select {
+	case node.eventChan <- event:
+	default:
+	}
+}
+
+// SetMinPeers sets how many reachable neighbors the node needs before it
+// stops redialing its bootnodes and remembered peers.
+func (node *Node) SetMinPeers(num int) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.minPeers = num
+}
+
+// bootstrap adds remembered peers and bootnodes to the neighbor list,
+// remembered peers first so that bootnodes stay in front.
+func (node *Node) bootstrap() {
+	if node.peerStore != nil {
+		stored := node.peerStore.Peers(neighborListCap)
+		for i := len(stored) - 1; i >= 0; i-- {
+			node.addPeer(stored[i])
+		}
+	}
+	node.lock.Lock()
+	bootnodes := node.bootnodes
+	node.lock.Unlock()
+	for i := range bootnodes {
+		node.addPeer(bootnodes[i])
+	}
+}
+
+type isolationState struct {
+	isolated    bool
+	attempts    int
+	nextAttempt time.Time
+}
+
+func newIsolationState() *isolationState {
+	return &isolationState{}
+}
+
+func (node *Node) checkIsolation(state *isolationState) {
+	node.lock.Lock()
+	minPeers := node.minPeers
+	node.lock.Unlock()
+
+	peers := node.neighbors.LenReachable()
+	if peers >= minPeers {
+		if state.isolated {
+			log.Printf("[gossip] node %s recovered with %d peers", node.NodeId().String(), peers)
+			node.emit(EventRecovered, peers)
+		}
+		*state = isolationState{}
+		return
+	}
+
+	if !state.isolated {
+		log.Printf("[gossip] node %s is isolated with %d peers", node.NodeId().String(), peers)
+		node.emit(EventIsolated, peers)
+		state.isolated = true
+	}
+	if time.Now().Before(state.nextAttempt) {
+		return
+	}
+	node.bootstrap()
+	state.nextAttempt = time.Now().Add(backoff(state.attempts))
+	state.attempts++
+}
+
+// backoff doubles the delay with each attempt and picks a random point in
+// its upper half, so that nodes cut off together do not redial together.
+func backoff(attempts int) time.Duration {
+	delay := rebootstrapBaseDelay
+	for i := 0; i < attempts && delay < rebootstrapMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > rebootstrapMaxDelay {
+		delay = rebootstrapMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
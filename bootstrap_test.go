@@ -0,0 +1,112 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func expectEvent(t *testing.T, node *Node, eventType EventType) Event {
+	select {
+	case event := <-node.GetEventChan():
+		if event.Type != eventType {
+			t.Fatalf("expected event %s, got %s", eventType, event.Type)
+		}
+		return event
+	default:
+		t.Fatalf("expected event %s, got none", eventType)
+	}
+	return Event{}
+}
+
+func expectNoEvent(t *testing.T, node *Node) {
+	select {
+	case event := <-node.GetEventChan():
+		t.Fatalf("unexpected event %s", event.Type)
+	default:
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	// nothing listens on these, and dialing does not block
+	peers := []NodeId{NewNodeId("127.0.0.1:9101"), NewNodeId("127.0.0.1:9102")}
+	node.lock.Lock()
+	node.bootnodes = peers
+	node.lock.Unlock()
+	node.bootstrap()
+	state := newIsolationState()
+
+	node.checkIsolation(state)
+	expectNoEvent(t, node)
+	if state.isolated {
+		t.Fatal("node with reachable peers is isolated")
+	}
+
+	// every known peer fails its call
+	for _, peer := range peers {
+		node.neighbors.Reconnect(peer)
+	}
+	if n := node.neighbors.LenReachable(); n != 0 {
+		t.Fatalf("%d peers reachable after all failed", n)
+	}
+	node.checkIsolation(state)
+	event := expectEvent(t, node, EventIsolated)
+	if event.Peers != 0 {
+		t.Errorf("isolated with %d peers", event.Peers)
+	}
+	if !state.isolated || state.attempts != 1 || !state.nextAttempt.After(time.Now()) {
+		t.Fatalf("no rebootstrap scheduled: %+v", state)
+	}
+	// still isolated, but reported once and redialed only after the backoff
+	nextAttempt := state.nextAttempt
+	node.checkIsolation(state)
+	expectNoEvent(t, node)
+	if state.attempts != 1 || state.nextAttempt != nextAttempt {
+		t.Fatalf("rebootstrapped before the backoff passed: %+v", state)
+	}
+
+	// a peer answers
+	node.recordSuccess(peers[0])
+	node.checkIsolation(state)
+	event = expectEvent(t, node, EventRecovered)
+	if event.Peers != 1 {
+		t.Errorf("recovered with %d peers", event.Peers)
+	}
+	if state.isolated || state.attempts != 0 {
+		t.Fatalf("isolation state not reset: %+v", state)
+	}
+	node.checkIsolation(state)
+	expectNoEvent(t, node)
+}
+
+func TestIsolationRedialsBootnodes(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	bootnode := NewNodeId("127.0.0.1:9103")
+	node.lock.Lock()
+	node.bootnodes = []NodeId{bootnode}
+	node.lock.Unlock()
+	state := newIsolationState()
+
+	node.checkIsolation(state)
+	expectEvent(t, node, EventIsolated)
+	if _, err := node.neighbors.GetConn(bootnode); err != nil {
+		t.Fatal("bootnode not redialed")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts := 0; attempts < 20; attempts++ {
+		delay := rebootstrapBaseDelay << uint(attempts)
+		if attempts > 10 || delay > rebootstrapMaxDelay {
+			delay = rebootstrapMaxDelay
+		}
+		for i := 0; i < 10; i++ {
+			d := backoff(attempts)
+			if d < delay/2 || d >= delay {
+				t.Fatalf("backoff(%d) = %s, expected in [%s, %s)", attempts, d, delay/2, delay)
+			}
+		}
+	}
+}
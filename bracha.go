@@ -0,0 +1,452 @@
+package gossip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"google.golang.org/grpc"
+)
+
+const brachaInstanceTTL = 10 * time.Minute
+const maxBrachaInstances = 4096
+const brachaRetryDelay = time.Second
+const maxBrachaRetryDelay = 30 * time.Second
+const brachaTimeout = 5 * time.Second
+
+// messages are refused once older than brachaMaxAge, well before their nonce
+// is forgotten, so that a replay cannot be delivered again. Origins may be
+// ahead by up to brachaMaxSkew.
+const brachaMaxAge = 5 * time.Minute
+const brachaMaxSkew = time.Minute
+
+// delivered nonces are kept per origin, so that no origin can push out the
+// record of another's
+const maxBrachaDelivered = 4096
+
+// brachaDelivered holds the nonces an origin's delivered messages were
+// timestamped with. Messages timestamped at or before floor are refused, since
+// their nonces may have been forgotten.
+type brachaDelivered struct {
+	nonces map[uint64]int64
+	floor  int64
+}
+
+// SetIdentity sets the key the node signs reliable broadcast messages with.
+// Its public key has to be part of the membership of every member.
+func (node *Node) SetIdentity(key *ecdsa.PrivateKey) {
+	node.bracha.lock.Lock()
+	defer node.bracha.lock.Unlock()
+	node.bracha.key = key
+}
+
+// SetMembership sets the nodes that take part in reliable broadcast, and
+// the public keys they sign with. Every member must be configured with the
+// same membership.
+func (node *Node) SetMembership(members map[NodeId]*ecdsa.PublicKey) {
+	node.bracha.lock.Lock()
+	defer node.bracha.lock.Unlock()
+	node.bracha.members = make(map[NodeId]*ecdsa.PublicKey, len(members))
+	for nodeId, key := range members {
+		node.bracha.members[nodeId] = key
+	}
+}
+
+// ReliableBroadcast publishes data to the membership with Bracha's reliable
+// broadcast: if any correct member delivers it, all correct members do, as
+// long as fewer than a third of the members are faulty. Unlike Gossip, it
+// sends to every member directly. Delivered messages appear on the message
+// channel like gossip does, and are ordered and logged the same way.
+func (node *Node) ReliableBroadcast(data []byte) error {
+	if err := node.checkMessageSize(len(data)); err != nil {
+		return err
+	}
+	nodeId := node.NodeId()
+	node.bracha.lock.Lock()
+	_, member := node.bracha.members[nodeId]
+	hasKey := node.bracha.key != nil
+	node.bracha.lock.Unlock()
+	if !hasKey || !member {
+		return errors.New(fmt.Sprintf("[gossip] Node %s is not a member with an identity", nodeId.String()))
+	}
+	msg := &BrachaMsg{
+		Topic:     node.topic,
+		Phase:     BrachaMsg_SEND,
+		Origin:    nodeId.String(),
+		Nonce:     rand.Uint64(),
+		Payload:   data,
+		Timestamp: time.Now().UnixNano(),
+	}
+	return node.sendBracha(msg)
+}
+
+func (node *Node) Bracha(ctx context.Context, msg *BrachaMsg) (*Empty, error) {
+	if msg.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if err := node.checkMessageSize(len(msg.Payload)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "[From %s] %s", node.NodeId().String(), ReasonTooLarge)
+	}
+	if err := node.bracha.verify(msg); err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "[From %s] %s", node.NodeId().String(), err.Error())
+	}
+	node.handleBracha(msg)
+	return &Empty{}, nil
+}
+
+// handleBracha advances the instance of a verified message, and sends or
+// delivers whatever that calls for.
+func (node *Node) handleBracha(msg *BrachaMsg) {
+	echo, ready, deliver := node.bracha.step(msg)
+	if echo {
+		node.sendBracha(node.brachaVote(msg, BrachaMsg_ECHO))
+	}
+	if ready {
+		node.sendBracha(node.brachaVote(msg, BrachaMsg_READY))
+	}
+	if deliver != nil {
+		node.release(&GossipData{
+			Topic:   msg.Topic,
+			NodeId:  msg.Origin,
+			Nonce:   msg.Nonce,
+			Payload: deliver,
+		})
+	}
+}
+
+func (node *Node) brachaVote(msg *BrachaMsg, phase BrachaMsg_Phase) *BrachaMsg {
+	return &BrachaMsg{
+		Topic:     msg.Topic,
+		Phase:     phase,
+		Origin:    msg.Origin,
+		Nonce:     msg.Nonce,
+		Payload:   msg.Payload,
+		Timestamp: msg.Timestamp,
+	}
+}
+
+// sendBracha signs msg and sends it to every member, including the node
+// itself.
+func (node *Node) sendBracha(msg *BrachaMsg) error {
+	nodeId := node.NodeId()
+	msg.Sender = nodeId.String()
+	if err := node.bracha.sign(msg); err != nil {
+		return err
+	}
+	for _, member := range node.bracha.memberIds() {
+		if member == nodeId {
+			go node.handleBracha(msg)
+			continue
+		}
+		go node.sendBrachaTo(member, msg)
+	}
+	return nil
+}
+
+// sendBrachaTo retries, backing off, until the member took msg or would
+// refuse it as stale, since the protocol assumes reliable links.
+func (node *Node) sendBrachaTo(member NodeId, msg *BrachaMsg) {
+	expiry := time.Unix(0, msg.Timestamp).Add(brachaMaxAge)
+	delay := brachaRetryDelay
+	for {
+		conn, err := node.bracha.getConn(member)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), brachaTimeout)
+			_, err = NewGossipClient(conn).Bracha(ctx, msg)
+			cancel()
+			if err == nil {
+				return
+			}
+			if code := status.Code(err); code == codes.PermissionDenied || code == codes.FailedPrecondition || code == codes.InvalidArgument {
+				return
+			}
+		}
+		if time.Now().Add(delay).After(expiry) || !node.sleep(delay) {
+			return
+		}
+		log.Printf("[gossip] Retrying %s to member %s: %s", msg.Phase.String(), member.String(), err.Error())
+		delay *= 2
+		if delay > maxBrachaRetryDelay {
+			delay = maxBrachaRetryDelay
+		}
+	}
+}
+
+type brachaInstance struct {
+	payloads  map[string][]byte
+	echoes    map[string]int
+	readies   map[string]int
+	echoFrom  map[string]bool
+	readyFrom map[string]bool
+	echoed    bool
+	readied   bool
+	delivered bool
+	updated   time.Time
+}
+
+// bracha is the state of reliable broadcast: the membership, the identity of
+// the node, the running instances, one per origin and nonce, and the nonces
+// delivered recently.
+type bracha struct {
+	key       *ecdsa.PrivateKey
+	members   map[NodeId]*ecdsa.PublicKey
+	instances map[string]*brachaInstance
+	delivered map[string]*brachaDelivered
+	conns     map[NodeId]*grpc.ClientConn
+	lock      *sync.Mutex
+}
+
+func newBracha() *bracha {
+	return &bracha{
+		members:   make(map[NodeId]*ecdsa.PublicKey),
+		instances: make(map[string]*brachaInstance),
+		delivered: make(map[string]*brachaDelivered),
+		conns:     make(map[NodeId]*grpc.ClientConn),
+		lock:      &sync.Mutex{},
+	}
+}
+
+// quorums returns how many echoes make a member ready, how many readies
+// make it ready as well, and how many readies make it deliver.
+func (b *bracha) quorums() (int, int, int) {
+	n := len(b.members)
+	f := (n - 1) / 3
+	return (n+f)/2 + 1, f + 1, 2*f + 1
+}
+
+func (b *bracha) memberIds() []NodeId {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ids := make([]NodeId, 0, len(b.members))
+	for nodeId := range b.members {
+		ids = append(ids, nodeId)
+	}
+	return ids
+}
+
+func (b *bracha) getConn(member NodeId) (*grpc.ClientConn, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if conn, ok := b.conns[member]; ok {
+		return conn, nil
+	}
+	conn, err := member.Dial()
+	if err != nil {
+		return nil, err
+	}
+	b.conns[member] = conn
+	return conn, nil
+}
+
+func (b *bracha) close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for member, conn := range b.conns {
+		conn.Close()
+		delete(b.conns, member)
+	}
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func brachaDigest(msg *BrachaMsg) []byte {
+	payload := sha256.Sum256(msg.Payload)
+	hash := sha256.New()
+	for _, field := range []string{msg.Topic, msg.Phase.String(), msg.Origin, strconv.FormatUint(msg.Nonce, 10), strconv.FormatInt(msg.Timestamp, 10), string(payload[:]), msg.Sender} {
+		hash.Write(UInt64ToBytes(uint64(len(field))))
+		hash.Write([]byte(field))
+	}
+	return hash.Sum(nil)
+}
+
+func (b *bracha) sign(msg *BrachaMsg) error {
+	b.lock.Lock()
+	key := b.key
+	b.lock.Unlock()
+	if key == nil {
+		return errors.New("[gossip] No identity to sign with")
+	}
+	r, s, err := ecdsa.Sign(crand.Reader, key, brachaDigest(msg))
+	if err != nil {
+		return err
+	}
+	msg.Signature, err = asn1.Marshal(ecdsaSignature{r, s})
+	return err
+}
+
+// verify checks that msg is signed by a member, that only the origin sends
+// its message, and that the message is recent.
+func (b *bracha) verify(msg *BrachaMsg) error {
+	b.lock.Lock()
+	key, ok := b.members[NodeId(msg.Sender)]
+	_, originOk := b.members[NodeId(msg.Origin)]
+	b.lock.Unlock()
+	if !ok || !originOk {
+		return errors.New("not a member")
+	}
+	if msg.Phase == BrachaMsg_SEND && msg.Sender != msg.Origin {
+		return errors.New("sent on behalf of another member")
+	}
+	var signature ecdsaSignature
+	if rest, err := asn1.Unmarshal(msg.Signature, &signature); err != nil || len(rest) != 0 || signature.R == nil || signature.S == nil {
+		return errors.New("malformed signature")
+	}
+	if !ecdsa.Verify(key, brachaDigest(msg), signature.R, signature.S) {
+		return errors.New("invalid signature")
+	}
+	age := time.Since(time.Unix(0, msg.Timestamp))
+	if age > brachaMaxAge || age < -brachaMaxSkew {
+		return errors.New("stale message")
+	}
+	return nil
+}
+
+// step applies a verified message to its instance. It returns whether the
+// node has to echo and to become ready, and the payload to deliver, if any.
+// Members only count the first echo and ready of every sender, and ignore
+// messages already delivered.
+func (b *bracha) step(msg *BrachaMsg) (bool, bool, []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.truncate()
+	if b.isDelivered(msg.Origin, msg.Nonce, msg.Timestamp) {
+		return false, false, nil
+	}
+	key := msg.Origin + "/" + strconv.FormatUint(msg.Nonce, 10) + "/" + strconv.FormatInt(msg.Timestamp, 10)
+	instance, ok := b.instances[key]
+	if !ok {
+		if len(b.instances) >= maxBrachaInstances {
+			b.evictOldest()
+		}
+		instance = &brachaInstance{
+			payloads:  make(map[string][]byte),
+			echoes:    make(map[string]int),
+			readies:   make(map[string]int),
+			echoFrom:  make(map[string]bool),
+			readyFrom: make(map[string]bool),
+		}
+		b.instances[key] = instance
+	}
+	instance.updated = time.Now()
+	payloadDigest := sha256.Sum256(msg.Payload)
+	digest := string(payloadDigest[:])
+
+	echo, ready := false, false
+	echoQuorum, readyQuorum, deliverQuorum := b.quorums()
+	switch msg.Phase {
+	case BrachaMsg_SEND:
+		if !instance.echoed {
+			instance.echoed = true
+			echo = true
+		}
+		return echo, ready, nil
+	case BrachaMsg_ECHO:
+		if instance.echoFrom[msg.Sender] {
+			return false, false, nil
+		}
+		instance.echoFrom[msg.Sender] = true
+		instance.payloads[digest] = msg.Payload
+		instance.echoes[digest]++
+		if instance.echoes[digest] >= echoQuorum && !instance.readied {
+			instance.readied = true
+			ready = true
+		}
+	case BrachaMsg_READY:
+		if instance.readyFrom[msg.Sender] {
+			return false, false, nil
+		}
+		instance.readyFrom[msg.Sender] = true
+		instance.payloads[digest] = msg.Payload
+		instance.readies[digest]++
+		if instance.readies[digest] >= readyQuorum && !instance.readied {
+			instance.readied = true
+			ready = true
+		}
+		if instance.readies[digest] >= deliverQuorum && !instance.delivered {
+			instance.delivered = true
+			b.markDelivered(msg.Origin, msg.Nonce, msg.Timestamp)
+			return echo, ready, instance.payloads[digest]
+		}
+	}
+	return echo, ready, nil
+}
+
+func (b *bracha) evictOldest() {
+	oldest := ""
+	for key, instance := range b.instances {
+		if oldest == "" || instance.updated.Before(b.instances[oldest].updated) {
+			oldest = key
+		}
+	}
+	delete(b.instances, oldest)
+}
+
+func (b *bracha) isDelivered(origin string, nonce uint64, timestamp int64) bool {
+	delivered, ok := b.delivered[origin]
+	if !ok {
+		return false
+	}
+	_, ok = delivered.nonces[nonce]
+	return ok || timestamp <= delivered.floor
+}
+
+// markDelivered records a delivered nonce of origin. When the origin has too
+// many, its oldest one is forgotten and its timestamp becomes the floor.
+func (b *bracha) markDelivered(origin string, nonce uint64, timestamp int64) {
+	delivered, ok := b.delivered[origin]
+	if !ok {
+		delivered = &brachaDelivered{nonces: make(map[uint64]int64)}
+		b.delivered[origin] = delivered
+	}
+	if len(delivered.nonces) >= maxBrachaDelivered {
+		var oldest uint64
+		oldestTimestamp := int64(0)
+		for n, t := range delivered.nonces {
+			if oldestTimestamp == 0 || t < oldestTimestamp {
+				oldest, oldestTimestamp = n, t
+			}
+		}
+		delete(delivered.nonces, oldest)
+		if oldestTimestamp > delivered.floor {
+			delivered.floor = oldestTimestamp
+		}
+	}
+	delivered.nonces[nonce] = timestamp
+}
+
+// truncate drops expired instances, and delivered nonces old enough that
+// their messages are refused as stale anyway.
+func (b *bracha) truncate() {
+	current := time.Now()
+	for key, instance := range b.instances {
+		if current.Sub(instance.updated) > brachaInstanceTTL {
+			delete(b.instances, key)
+		}
+	}
+	stale := current.Add(-brachaInstanceTTL).UnixNano()
+	for origin, delivered := range b.delivered {
+		for nonce, timestamp := range delivered.nonces {
+			if timestamp < stale {
+				delete(delivered.nonces, nonce)
+			}
+		}
+		if len(delivered.nonces) == 0 && delivered.floor < stale {
+			delete(b.delivered, origin)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package gossip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReliableBroadcast(t *testing.T) {
+	var nodes [4]*Node
+	keys := make(map[NodeId]*ecdsa.PrivateKey)
+	members := make(map[NodeId]*ecdsa.PublicKey)
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i].SetIdentity(key)
+		keys[nodes[i].NodeId()] = key
+		members[nodes[i].NodeId()] = &key.PublicKey
+	}
+	outsider := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := outsider.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer outsider.Close()
+	for i := range nodes {
+		nodes[i].SetMembership(members)
+	}
+	outsider.SetMembership(members)
+	if err := outsider.ReliableBroadcast([]byte("forged")); err == nil {
+		t.Error("non-member broadcast")
+	}
+
+	if err := nodes[0].ReliableBroadcast([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	for i := range nodes {
+		select {
+		case msg := <-nodes[i].GetMsgChan():
+			if string(msg) != "hello" {
+				t.Errorf("node %d delivered %s", i, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("node %d did not deliver", i)
+		}
+	}
+
+	// votes signed with the wrong key are refused
+	vote := &BrachaMsg{Topic: "test topic", Phase: BrachaMsg_SEND, Origin: nodes[1].NodeId().String(), Nonce: 1, Payload: []byte("forged")}
+	outsider.SetIdentity(keys[nodes[0].NodeId()])
+	vote.Sender = vote.Origin
+	if err := outsider.bracha.sign(vote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodes[2].Bracha(context.Background(), vote); err == nil {
+		t.Error("accepted a message with an invalid signature")
+	}
+
+	// a replay is not delivered again once its instance is gone, and stale
+	// messages are refused
+	target := nodes[3]
+	ready := func(i int, timestamp time.Time) *BrachaMsg {
+		vote := &BrachaMsg{
+			Topic:     "test topic",
+			Phase:     BrachaMsg_READY,
+			Origin:    nodes[0].NodeId().String(),
+			Nonce:     7,
+			Payload:   []byte("once"),
+			Timestamp: timestamp.UnixNano(),
+			Sender:    nodes[i].NodeId().String(),
+		}
+		signer := &bracha{key: keys[nodes[i].NodeId()], lock: &sync.Mutex{}}
+		if err := signer.sign(vote); err != nil {
+			t.Fatal(err)
+		}
+		return vote
+	}
+	sent := time.Now()
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 3; i++ {
+			if _, err := target.Bracha(context.Background(), ready(i, sent)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		target.bracha.lock.Lock()
+		target.bracha.instances = make(map[string]*brachaInstance)
+		target.bracha.lock.Unlock()
+	}
+	select {
+	case msg := <-target.GetMsgChan():
+		if string(msg) != "once" {
+			t.Errorf("delivered %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not deliver")
+	}
+	select {
+	case msg := <-target.GetMsgChan():
+		t.Errorf("replay delivered %s", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if _, err := target.Bracha(context.Background(), ready(0, time.Now().Add(-brachaMaxAge-time.Second))); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("accepted a stale message: %v", err)
+	}
+}
+
+func TestBrachaDeliveredFloor(t *testing.T) {
+	b := newBracha()
+	for i := 0; i < maxBrachaDelivered+1; i++ {
+		b.markDelivered("origin", uint64(i), int64(i+1))
+	}
+	if len(b.delivered["origin"].nonces) != maxBrachaDelivered {
+		t.Fatalf("%d nonces kept", len(b.delivered["origin"].nonces))
+	}
+	// the forgotten nonce is still refused, through its timestamp
+	if !b.isDelivered("origin", 0, 1) || !b.isDelivered("origin", 5, 6) {
+		t.Error("delivered nonce accepted again")
+	}
+	if b.isDelivered("origin", uint64(maxBrachaDelivered+1), int64(maxBrachaDelivered+2)) || b.isDelivered("other", 0, 1) {
+		t.Error("new nonce refused")
+	}
+}
+
+func TestBrachaRetransmission(t *testing.T) {
+	// the last member only comes up after the first few sends to it failed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	late := NewNodeId(listener.Addr().String())
+	listener.Close()
+
+	var nodes [4]*Node
+	members := make(map[NodeId]*ecdsa.PublicKey)
+	for i := range nodes {
+		if i < len(nodes)-1 {
+			nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+			if err := nodes[i].Start(); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			nodes[i] = New(late, "test topic")
+		}
+		defer nodes[i].Close()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i].SetIdentity(key)
+		members[nodes[i].NodeId()] = &key.PublicKey
+	}
+	for i := range nodes {
+		nodes[i].SetMembership(members)
+	}
+
+	if err := nodes[0].ReliableBroadcast([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(4 * brachaRetryDelay)
+	if err := nodes[3].Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-nodes[3].GetMsgChan():
+		if string(msg) != "hello" {
+			t.Errorf("delivered %s", msg)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("late member did not deliver")
+	}
+}
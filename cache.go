@@ -0,0 +1,111 @@
+package gossip
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const messageCacheTTL = 60 * time.Second
+const messageCacheBytes = 64 << 20
+
+type cachedMessage struct {
+	hash    string
+	data    *GossipData
+	created time.Time
+}
+
+// MessageCache keeps recently seen messages by hash, and sequenced messages
+// also by origin and sequence number, so that peers can fetch what they
+// missed. Messages expire after the ttl, or oldest first when more
+// than maxBytes of payload are cached.
+type MessageCache struct {
+	ttl      time.Duration
+	maxBytes int
+	bytes    int
+	order    *list.List
+	messages map[string]*list.Element
+	sequence map[string]string
+	lock     *sync.Mutex
+}
+
+func NewMessageCache(ttl time.Duration, maxBytes int) *MessageCache {
+	return &MessageCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		messages: make(map[string]*list.Element),
+		sequence: make(map[string]string),
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (mc *MessageCache) Add(data *GossipData) {
+	hash := data.Hash()
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if _, ok := mc.messages[hash]; ok {
+		return
+	}
+	mc.messages[hash] = mc.order.PushBack(&cachedMessage{hash, data, time.Now()})
+	if data.Seq != 0 {
+		mc.sequence[sequenceKey(data.NodeId, data.Epoch, data.Seq)] = hash
+	}
+	mc.bytes += len(data.Payload)
+	mc.truncate()
+}
+
+func (mc *MessageCache) Get(hash string) (*GossipData, bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.truncate()
+	if e, ok := mc.messages[hash]; ok {
+		return e.Value.(*cachedMessage).data, true
+	}
+	return nil, false
+}
+
+// Hashes returns the hashes of the cached messages.
+func (mc *MessageCache) Hashes() []string {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.truncate()
+	hashes := make([]string, 0, len(mc.messages))
+	for hash := range mc.messages {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// GetSeq returns the message published by origin with the sequence number
+// seq in epoch.
+func (mc *MessageCache) GetSeq(origin string, epoch, seq uint64) (*GossipData, bool) {
+	mc.lock.Lock()
+	hash, ok := mc.sequence[sequenceKey(origin, epoch, seq)]
+	mc.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return mc.Get(hash)
+}
+
+func sequenceKey(origin string, epoch, seq uint64) string {
+	return origin + "/" + strconv.FormatUint(epoch, 10) + "/" + strconv.FormatUint(seq, 10)
+}
+
+func (mc *MessageCache) truncate() {
+	current := time.Now()
+	for e := mc.order.Front(); e != nil; e = mc.order.Front() {
+		message := e.Value.(*cachedMessage)
+		if mc.bytes <= mc.maxBytes && current.Sub(message.created) <= mc.ttl {
+			return
+		}
+		mc.bytes -= len(message.data.Payload)
+		delete(mc.messages, message.hash)
+		if message.data.Seq != 0 {
+			delete(mc.sequence, sequenceKey(message.data.NodeId, message.data.Epoch, message.data.Seq))
+		}
+		mc.order.Remove(e)
+	}
+}
@@ -0,0 +1,348 @@
+package gossip
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderingCausal delivers a message only after the messages its publisher
+// had delivered before publishing it, whoever published those.
+const OrderingCausal Ordering = OrderingFIFO + 1
+
+// messages may depend on at most maxCausalDeps others, which is as many as
+// a frontier holds
+const maxCausalDeps = 64
+const maxCausalPending = 4096
+
+const ReasonTooManyDeps = "too many dependencies"
+
+// causalHistory is how long delivered messages are remembered. Dependencies
+// on older messages of an origin are assumed to be delivered.
+const causalHistory = 10 * time.Minute
+
+func ref(data *GossipData) *MessageRef {
+	return &MessageRef{
+		Origin: data.NodeId,
+		Epoch:  data.Epoch,
+		Seq:    data.Seq,
+	}
+}
+
+func (r *MessageRef) key() string {
+	return sequenceKey(r.Origin, r.Epoch, r.Seq)
+}
+
+type causalMessage struct {
+	data     *GossipData
+	missing  map[string]*MessageRef
+	received time.Time
+	lastPull time.Time
+}
+
+type delivery struct {
+	ref  *MessageRef
+	time time.Time
+}
+
+type watermark struct {
+	seq     uint64
+	updated time.Time
+}
+
+// causal holds messages back until their dependencies are delivered.
+// Published messages depend on the frontier of what was delivered before,
+// which is the delivered messages no other delivered message depends on.
+type causal struct {
+	enabled    bool
+	policy     GapPolicy
+	gapTimeout time.Duration
+	delivered  map[string]*delivery
+	forgotten  map[string]*watermark
+	pending    map[string]*causalMessage
+	waiters    map[string][]string
+	frontier   map[string]*MessageRef
+	// passed are messages that were not delivered but count as delivered.
+	// Should another message with the same reference turn up, it was the
+	// genuine one and is delivered late.
+	passed map[string]bool
+	lock   *sync.Mutex
+}
+
+func newCausal() *causal {
+	return &causal{
+		gapTimeout: defaultGapTimeout,
+		delivered:  make(map[string]*delivery),
+		forgotten:  make(map[string]*watermark),
+		pending:    make(map[string]*causalMessage),
+		waiters:    make(map[string][]string),
+		frontier:   make(map[string]*MessageRef),
+		passed:     make(map[string]bool),
+		lock:       &sync.Mutex{},
+	}
+}
+
+func (c *causal) setEnabled(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.enabled = enabled
+}
+
+func (c *causal) setGapPolicy(policy GapPolicy, timeout time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.policy = policy
+	c.gapTimeout = timeout
+}
+
+// publish returns the dependencies of a message the node publishes, and
+// records it as delivered. It returns nil if causal ordering is off.
+func (c *causal) publish(data *GossipData) []*MessageRef {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.enabled {
+		return nil
+	}
+	deps := make([]*MessageRef, 0, len(c.frontier))
+	for _, dep := range c.frontier {
+		deps = append(deps, dep)
+	}
+	c.markDelivered(ref(data), deps)
+	return deps
+}
+
+func (c *causal) isDelivered(dep *MessageRef) bool {
+	if _, ok := c.delivered[dep.key()]; ok {
+		return true
+	}
+	w, ok := c.forgotten[sequenceKey(dep.Origin, dep.Epoch, 0)]
+	return ok && dep.Seq <= w.seq
+}
+
+// add delivers data to msgChan once its dependencies are delivered, along
+// with whatever waited for it. It returns false if causal ordering is off.
+func (c *causal) add(data *GossipData, emit func(*GossipData)) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.enabled {
+		return false
+	}
+	r := ref(data)
+	key := r.key()
+	if c.passed[key] {
+		delete(c.passed, key)
+		emit(data)
+		return true
+	}
+	if _, ok := c.pending[key]; ok || c.isDelivered(r) {
+		return true
+	}
+	missing := make(map[string]*MessageRef)
+	for _, dep := range data.Deps {
+		if !c.isDelivered(dep) {
+			missing[dep.key()] = dep
+		}
+	}
+	if len(missing) == 0 {
+		c.deliver(data, emit)
+		return true
+	}
+	for depKey := range missing {
+		c.waiters[depKey] = append(c.waiters[depKey], key)
+	}
+	c.pending[key] = &causalMessage{
+		data:     data,
+		missing:  missing,
+		received: time.Now(),
+	}
+	for len(c.pending) > maxCausalPending {
+		c.forceOldest(emit)
+	}
+	return true
+}
+
+// pass counts data as delivered without delivering it, and delivers the
+// pending messages that only waited for it. Passed messages do not become
+// dependencies of the node's own. It returns false if causal ordering is
+// off.
+func (c *causal) pass(data *GossipData, emit func(*GossipData)) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.enabled {
+		return false
+	}
+	r := ref(data)
+	key := r.key()
+	if _, ok := c.pending[key]; ok || c.isDelivered(r) {
+		return true
+	}
+	c.delivered[key] = &delivery{r, time.Now()}
+	c.passed[key] = true
+	for _, unblocked := range c.unblock(key) {
+		c.deliver(unblocked, emit)
+	}
+	return true
+}
+
+// deliver delivers data and the pending messages that only waited for it.
+func (c *causal) deliver(data *GossipData, emit func(*GossipData)) {
+	queue := []*GossipData{data}
+	for len(queue) > 0 {
+		data, queue = queue[0], queue[1:]
+		key := ref(data).key()
+		delete(c.pending, key)
+		c.markDelivered(ref(data), data.Deps)
+		emit(data)
+		queue = append(queue, c.unblock(key)...)
+	}
+}
+
+// unblock returns the pending messages that only waited for key.
+func (c *causal) unblock(key string) []*GossipData {
+	var unblocked []*GossipData
+	for _, waiting := range c.waiters[key] {
+		message, ok := c.pending[waiting]
+		if !ok {
+			continue
+		}
+		delete(message.missing, key)
+		if len(message.missing) == 0 {
+			unblocked = append(unblocked, message.data)
+		}
+	}
+	delete(c.waiters, key)
+	return unblocked
+}
+
+func (c *causal) markDelivered(r *MessageRef, deps []*MessageRef) {
+	c.delivered[r.key()] = &delivery{r, time.Now()}
+	for _, dep := range deps {
+		delete(c.frontier, dep.key())
+	}
+	c.frontier[r.key()] = r
+	for len(c.frontier) > maxCausalDeps {
+		// keep the newest messages, which most likely depend on the others
+		oldest := ""
+		for key := range c.frontier {
+			if oldest == "" || c.delivered[key].time.Before(c.delivered[oldest].time) {
+				oldest = key
+			}
+		}
+		delete(c.frontier, oldest)
+	}
+}
+
+// force delivers a pending message without waiting for its dependencies
+// anymore.
+func (c *causal) force(key string, emit func(*GossipData)) {
+	message := c.pending[key]
+	for depKey := range message.missing {
+		waiters := c.waiters[depKey]
+		for i := range waiters {
+			if waiters[i] == key {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(waiters) == 0 {
+			delete(c.waiters, depKey)
+		} else {
+			c.waiters[depKey] = waiters
+		}
+	}
+	c.deliver(message.data, emit)
+}
+
+func (c *causal) forceOldest(emit func(*GossipData)) {
+	oldest := ""
+	for key, message := range c.pending {
+		if oldest == "" || message.received.Before(c.pending[oldest].received) {
+			oldest = key
+		}
+	}
+	c.force(oldest, emit)
+}
+
+// check delivers messages whose dependencies could not be recovered in
+// time, compacts the history and returns the dependencies to pull.
+func (c *causal) check(emit func(*GossipData)) []gap {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	current := time.Now()
+
+	if c.policy == GapSkip {
+		var expired []string
+		for key, message := range c.pending {
+			if current.Sub(message.received) > c.gapTimeout {
+				expired = append(expired, key)
+			}
+		}
+		sort.Slice(expired, func(i, j int) bool {
+			return c.pending[expired[i]].received.Before(c.pending[expired[j]].received)
+		})
+		for _, key := range expired {
+			if _, ok := c.pending[key]; ok {
+				c.force(key, emit)
+			}
+		}
+	}
+
+	c.compact(current)
+
+	missing := make(map[string]*gap)
+	pulled := make(map[string]bool)
+	for _, message := range c.pending {
+		if current.Sub(message.received) < gapPullDelay || current.Sub(message.lastPull) < gapPullInterval {
+			continue
+		}
+		message.lastPull = current
+		for depKey, dep := range message.missing {
+			if pulled[depKey] {
+				continue
+			}
+			pulled[depKey] = true
+			stream := sequenceKey(dep.Origin, dep.Epoch, 0)
+			g, ok := missing[stream]
+			if !ok {
+				g = &gap{origin: dep.Origin, epoch: dep.Epoch}
+				missing[stream] = g
+			}
+			if len(g.seqs) < maxGapPulls {
+				g.seqs = append(g.seqs, dep.Seq)
+			}
+		}
+	}
+	gaps := make([]gap, 0, len(missing))
+	for _, g := range missing {
+		gaps = append(gaps, *g)
+	}
+	return gaps
+}
+
+// compact forgets old deliveries. It keeps a watermark per origin instead,
+// which is dropped as well once the origin stopped publishing for a while.
+func (c *causal) compact(current time.Time) {
+	for key, delivered := range c.delivered {
+		if current.Sub(delivered.time) <= causalHistory {
+			continue
+		}
+		delete(c.delivered, key)
+		delete(c.frontier, key)
+		delete(c.passed, key)
+		stream := sequenceKey(delivered.ref.Origin, delivered.ref.Epoch, 0)
+		w, ok := c.forgotten[stream]
+		if !ok {
+			w = &watermark{}
+			c.forgotten[stream] = w
+		}
+		if delivered.ref.Seq > w.seq {
+			w.seq = delivered.ref.Seq
+		}
+		w.updated = current
+	}
+	for stream, w := range c.forgotten {
+		if current.Sub(w.updated) > causalHistory {
+			delete(c.forgotten, stream)
+		}
+	}
+}
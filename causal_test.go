@@ -0,0 +1,84 @@
+package gossip
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCausal(t *testing.T) {
+	msgChan := make(chan []byte, 16)
+	emit := emitTo(msgChan)
+	c := newCausal()
+	c.setEnabled(true)
+	c.setGapPolicy(GapSkip, time.Hour)
+
+	question := &GossipData{NodeId: "a", Epoch: 1, Seq: 1, Payload: []byte("question")}
+	// b publishes a reply after it delivered the question
+	b := newCausal()
+	b.setEnabled(true)
+	b.add(question, emitTo(make(chan []byte, 1)))
+	reply := &GossipData{NodeId: "b", Epoch: 1, Seq: 1, Payload: []byte("reply")}
+	reply.Deps = b.publish(reply)
+	if len(reply.Deps) != 1 || reply.Deps[0].key() != ref(question).key() {
+		t.Fatalf("unexpected dependencies %v", reply.Deps)
+	}
+	// the next message of b only depends on the reply
+	next := &GossipData{NodeId: "b", Epoch: 1, Seq: 2}
+	if deps := b.publish(next); len(deps) != 1 || deps[0].key() != ref(reply).key() {
+		t.Fatalf("unexpected dependencies %v", deps)
+	}
+
+	c.add(reply, emit)
+	select {
+	case msg := <-msgChan:
+		t.Fatalf("%s delivered before its dependency", msg)
+	default:
+	}
+	c.pending[ref(reply).key()].received = time.Now().Add(-gapPullDelay)
+	if gaps := c.check(emit); len(gaps) != 1 || gaps[0].origin != "a" || len(gaps[0].seqs) != 1 || gaps[0].seqs[0] != 1 {
+		t.Errorf("unexpected gaps %v", gaps)
+	}
+	c.add(question, emit)
+	if msg := <-msgChan; string(msg) != "question" {
+		t.Errorf("unexpected message %s", msg)
+	}
+	if msg := <-msgChan; string(msg) != "reply" {
+		t.Errorf("unexpected message %s", msg)
+	}
+	if len(c.pending) != 0 || len(c.waiters) != 0 {
+		t.Error("delivered messages are still pending")
+	}
+
+	// dependencies that never arrive are given up on
+	orphan := &GossipData{NodeId: "c", Epoch: 1, Seq: 1, Payload: []byte("orphan"), Deps: []*MessageRef{{Origin: "d", Epoch: 1, Seq: 1}}}
+	c.add(orphan, emit)
+	c.setGapPolicy(GapSkip, 0)
+	c.check(emit)
+	if msg := <-msgChan; string(msg) != "orphan" {
+		t.Errorf("unexpected message %s", msg)
+	}
+	if len(c.waiters) != 0 {
+		t.Error("waiters of a skipped message remain")
+	}
+}
+
+func TestCausalDepsLimit(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	node.SetOrdering(OrderingCausal)
+	sender := New(NewNodeId("127.0.0.1:9151"), "test topic")
+	data, _ := sender.newGossipData([]byte("hello"))
+	for i := 0; i <= maxCausalDeps; i++ {
+		data.Deps = append(data.Deps, &MessageRef{Origin: "a", Epoch: 1, Seq: uint64(i + 1)})
+	}
+	res, err := node.SendData(context.Background(), data)
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonTooManyDeps {
+		t.Fatalf("expected too many dependencies, got %v, %v", res, err)
+	}
+	node.causal.lock.Lock()
+	defer node.causal.lock.Unlock()
+	if len(node.causal.pending) != 0 {
+		t.Error("message over the dependency limit was buffered")
+	}
+}
@@ -0,0 +1,112 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+const FeatureChannels = "channels"
+
+// internal channels of the node share this prefix
+const reservedChannelPrefix = "gossip/"
+
+// ChannelHandler handles a message gossiped on a channel by from.
+type ChannelHandler func(from NodeId, payload []byte)
+
+// channelHandler handles the messages gossiped on a channel. Messages on
+// channels are not delivered to the message channel of the node.
+type channelHandler func(data *GossipData)
+
+type channels struct {
+	handlers map[string]channelHandler
+	lock     *sync.Mutex
+}
+
+func newChannels() *channels {
+	return &channels{
+		handlers: make(map[string]channelHandler),
+		lock:     &sync.Mutex{},
+	}
+}
+
+// HandleChannel registers the handler for messages gossiped on channel. A
+// nil handler removes it. Channel names starting with "gossip/" are
+// reserved.
+func (node *Node) HandleChannel(channel string, handler ChannelHandler) error {
+	if err := checkChannel(channel); err != nil {
+		return err
+	}
+	if handler == nil {
+		node.channels.lock.Lock()
+		defer node.channels.lock.Unlock()
+		delete(node.channels.handlers, channel)
+		return nil
+	}
+	node.handleChannel(channel, func(data *GossipData) {
+		handler(NewNodeId(data.NodeId), data.Payload)
+	})
+	return nil
+}
+
+// GossipChannel gossips payload to the handlers of channel on every node,
+// including the node itself. Unlike Gossip, it does not go through the
+// message channel nor ordering.
+func (node *Node) GossipChannel(channel string, payload []byte) error {
+	if err := checkChannel(channel); err != nil {
+		return err
+	}
+	return node.gossipChannel(channel, payload)
+}
+
+// RequestChannel sends payload to the direct message handler of channel on
+// nodeId, and waits for its response.
+func (node *Node) RequestChannel(ctx context.Context, nodeId NodeId, channel string, payload []byte) ([]byte, error) {
+	if err := checkChannel(channel); err != nil {
+		return nil, err
+	}
+	return node.request(ctx, nodeId, channel, payload)
+}
+
+func checkChannel(channel string) error {
+	if strings.HasPrefix(channel, reservedChannelPrefix) {
+		return errors.New(fmt.Sprintf("[gossip] Channel %q is reserved", channel))
+	}
+	return nil
+}
+
+func (node *Node) handleChannel(channel string, handler channelHandler) {
+	node.channels.lock.Lock()
+	defer node.channels.lock.Unlock()
+	node.channels.handlers[channel] = handler
+}
+
+// gossipChannel gossips payload on channel. Channel messages are not
+// sequenced, so they do not hold up ordered delivery.
+func (node *Node) gossipChannel(channel string, payload []byte) error {
+	if err := node.checkMessageSize(len(payload)); err != nil {
+		return err
+	}
+	node.publish(&GossipData{
+		Topic:   node.topic,
+		NodeId:  node.NodeId().String(),
+		Nonce:   rand.Uint64(),
+		Payload: payload,
+		Channel: channel,
+	})
+	return nil
+}
+
+// dispatch hands a channel message to its handler. Messages on unknown
+// channels are dropped.
+func (node *Node) dispatch(data *GossipData) {
+	node.channels.lock.Lock()
+	handler, ok := node.channels.handlers[data.Channel]
+	node.channels.lock.Unlock()
+	if ok {
+		handler(data)
+	}
+}
@@ -0,0 +1,243 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultMaxMessageSize = 16 << 20
+const defaultChunkSize = 1 << 20
+const reassemblyTimeout = 30 * time.Second
+
+// fragments of up to this many messages of the largest size are buffered
+const reassemblyMessages = 4
+const maxFragments = 4096
+
+// rpcOverhead is what a message may add to its payload on the wire.
+const rpcOverhead = 64 << 10
+
+const FeatureChunking = "chunking"
+
+const ReasonTooLarge = "message too large"
+const ReasonMalformed = "malformed fragment"
+
+// SetMaxMessageSize sets the largest payload the node publishes or accepts.
+// It must be called before Listen or Start.
+func (node *Node) SetMaxMessageSize(size int) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.maxMessageSize = size
+	node.neighbors.SetMaxRecvMsgSize(size + rpcOverhead)
+	node.reassembler.SetMaxBytes(reassemblyMessages * size)
+}
+
+// SetChunkSize makes the node split payloads larger than size into fragments
+// of that size. Receivers reassemble them and deliver a single message. Zero
+// disables chunking. Fragments are only sent to peers that negotiated
+// FeatureChunking, the others get the whole message.
+func (node *Node) SetChunkSize(size int) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.chunkSize = size
+}
+
+func (node *Node) sizeLimits() (int, int) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.maxMessageSize, node.chunkSize
+}
+
+func (node *Node) checkMessageSize(size int) error {
+	maxSize, _ := node.sizeLimits()
+	if size > maxSize {
+		return errors.New(fmt.Sprintf("[gossip] Message of %d bytes exceeds the limit of %d bytes", size, maxSize))
+	}
+	return nil
+}
+
+// checkSize returns why data cannot be accepted, or an empty string.
+func (node *Node) checkSize(data *GossipData) string {
+	maxSize, _ := node.sizeLimits()
+	if len(data.Payload) > maxSize || data.Size > uint64(maxSize) {
+		return ReasonTooLarge
+	}
+	if len(data.Deps) > maxCausalDeps {
+		return ReasonTooManyDeps
+	}
+	if data.Epoch > uint64(time.Now().Add(maxEpochSkew).UnixNano()) {
+		return ReasonFutureEpoch
+	}
+	if fragment := data.Fragment; fragment != nil {
+		if fragment.Size > uint64(maxSize) {
+			return ReasonTooLarge
+		}
+		if fragment.Count > maxFragments || fragment.Index >= fragment.Count || uint64(len(data.Payload)) > fragment.Size {
+			return ReasonMalformed
+		}
+	}
+	return ""
+}
+
+// split cuts data into fragments if its payload exceeds the chunk size.
+func (node *Node) split(data *GossipData) []*GossipData {
+	_, chunkSize := node.sizeLimits()
+	if chunkSize <= 0 || len(data.Payload) <= chunkSize {
+		return []*GossipData{data}
+	}
+	if len(data.Payload) > maxFragments*chunkSize {
+		chunkSize = (len(data.Payload) + maxFragments - 1) / maxFragments
+	}
+	digest := sha256.Sum256(data.Payload)
+	count := (len(data.Payload) + chunkSize - 1) / chunkSize
+	parts := make([]*GossipData, count)
+	for i := range parts {
+		end := (i + 1) * chunkSize
+		if end > len(data.Payload) {
+			end = len(data.Payload)
+		}
+		part := *data
+		part.Payload = data.Payload[i*chunkSize : end]
+		part.Fragment = &Fragment{
+			Digest: digest[:],
+			Index:  uint32(i),
+			Count:  uint32(count),
+			Size:   uint64(len(data.Payload)),
+		}
+		parts[i] = &part
+	}
+	return parts
+}
+
+type reassembly struct {
+	parts    []*GossipData
+	received int
+	buffered int
+	created  time.Time
+}
+
+// Reassembler collects the fragments of messages until they are complete.
+// Incomplete messages are dropped after the timeout, or when fragments
+// beyond maxBytes are buffered, oldest first.
+type Reassembler struct {
+	timeout  time.Duration
+	maxBytes int
+	pending  map[string]*reassembly
+	buffered int
+	lock     *sync.Mutex
+}
+
+func NewReassembler(timeout time.Duration, maxBytes int) *Reassembler {
+	return &Reassembler{
+		timeout:  timeout,
+		maxBytes: maxBytes,
+		pending:  make(map[string]*reassembly),
+		lock:     &sync.Mutex{},
+	}
+}
+
+// SetMaxBytes changes how many bytes of fragments are buffered, dropping
+// the oldest incomplete messages beyond it.
+func (r *Reassembler) SetMaxBytes(maxBytes int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.maxBytes = maxBytes
+	for r.buffered > r.maxBytes {
+		if !r.evictOldest("") {
+			break
+		}
+	}
+}
+
+func fragmentKey(data *GossipData) string {
+	return data.NodeId + "/" + strconv.FormatUint(data.Nonce, 10) + "/" + hex.EncodeToString(data.Fragment.Digest)
+}
+
+// Add buffers a fragment, and reports false if a fragment was buffered at
+// its index already. Once all fragments of its message arrived and match the
+// digest, it returns the whole message and its fragments. If they do not
+// match, they are all dropped, so that the message can be reassembled from
+// fragments that arrive later.
+func (r *Reassembler) Add(data *GossipData) (*GossipData, []*GossipData, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.truncate()
+
+	key := fragmentKey(data)
+	fragment := data.Fragment
+	pending, ok := r.pending[key]
+	if !ok {
+		pending = &reassembly{
+			parts:   make([]*GossipData, fragment.Count),
+			created: time.Now(),
+		}
+		r.pending[key] = pending
+	}
+	if len(pending.parts) != int(fragment.Count) || pending.parts[fragment.Index] != nil {
+		return nil, nil, false
+	}
+	for r.buffered+len(data.Payload) > r.maxBytes {
+		if !r.evictOldest(key) {
+			break
+		}
+	}
+	pending.parts[fragment.Index] = data
+	pending.received++
+	pending.buffered += len(data.Payload)
+	r.buffered += len(data.Payload)
+	if pending.received < len(pending.parts) {
+		return nil, nil, true
+	}
+
+	r.drop(key)
+	payloads := make([][]byte, len(pending.parts))
+	for i := range pending.parts {
+		payloads[i] = pending.parts[i].Payload
+	}
+	payload := bytes.Join(payloads, nil)
+	digest := sha256.Sum256(payload)
+	if uint64(len(payload)) != fragment.Size || !bytes.Equal(digest[:], fragment.Digest) {
+		log.Printf("[gossip] Fragments of message from %s do not match its digest", data.NodeId)
+		return nil, nil, true
+	}
+	whole := *data
+	whole.Payload = payload
+	whole.Fragment = nil
+	return &whole, pending.parts, true
+}
+
+func (r *Reassembler) drop(key string) {
+	if pending, ok := r.pending[key]; ok {
+		r.buffered -= pending.buffered
+		delete(r.pending, key)
+	}
+}
+
+func (r *Reassembler) evictOldest(keep string) bool {
+	oldest := ""
+	for key, pending := range r.pending {
+		if key != keep && (oldest == "" || pending.created.Before(r.pending[oldest].created)) {
+			oldest = key
+		}
+	}
+	if oldest == "" {
+		return false
+	}
+	r.drop(oldest)
+	return true
+}
+
+func (r *Reassembler) truncate() {
+	current := time.Now()
+	for key, pending := range r.pending {
+		if current.Sub(pending.created) > r.timeout {
+			r.drop(key)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package gossip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+)
+
+const ReasonUndecodable = "cannot decode payload"
+
+// Codec compresses payloads. Decompress must fail rather than return more
+// than maxSize bytes.
+type Codec interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte, maxSize int) ([]byte, error)
+}
+
+var codecs = map[string]Codec{}
+var codecsLock = &sync.RWMutex{}
+
+// RegisterCodec makes a codec available to nodes created afterwards. Nodes
+// advertise the codecs they know in their handshake.
+func RegisterCodec(codec Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	codecs[codec.Name()] = codec
+}
+
+func GetCodec(name string) (Codec, bool) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+func codecFeatures() []string {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	features := make([]string, 0, len(codecs))
+	for name := range codecs {
+		features = append(features, codecFeature(name))
+	}
+	return features
+}
+
+// codecFeature is the feature a peer advertises to receive payloads
+// compressed with the named codec.
+func codecFeature(name string) string {
+	return FeatureCompression + "/" + name
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte, maxSize int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	ret, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) > maxSize {
+		return nil, errors.New(fmt.Sprintf("[gossip] Decompressed payload exceeds %d bytes", maxSize))
+	}
+	return ret, nil
+}
+
+func init() {
+	RegisterCodec(gzipCodec{})
+}
+
+// SetCompression makes the node compress the payloads it publishes with the
+// named codec, unless they are smaller than threshold bytes. Relays forward
+// compressed payloads as they are, and they are only decompressed for
+// delivery. Peers that did not negotiate the codec get messages
+// decompressed. An empty name disables compression.
+func (node *Node) SetCompression(name string, threshold int) error {
+	if name != "" {
+		if _, ok := GetCodec(name); !ok {
+			return errors.New(fmt.Sprintf("[gossip] Unknown codec %s", name))
+		}
+	}
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.codec = name
+	node.compressThreshold = threshold
+	return nil
+}
+
+// compress returns data with its payload compressed, if that is configured
+// and makes the payload smaller.
+func (node *Node) compress(data *GossipData) *GossipData {
+	node.lock.Lock()
+	name, threshold := node.codec, node.compressThreshold
+	node.lock.Unlock()
+	if name == "" || len(data.Payload) < threshold {
+		return data
+	}
+	codec, _ := GetCodec(name)
+	payload, err := codec.Compress(data.Payload)
+	if err != nil {
+		log.Printf("[gossip] Cannot compress payload with %s: %s", name, err.Error())
+		return data
+	}
+	if len(payload) >= len(data.Payload) {
+		return data
+	}
+	compressed := *data
+	compressed.Payload = payload
+	compressed.Codec = name
+	return &compressed
+}
+
+// decompress returns data with a plain payload.
+func (node *Node) decompress(data *GossipData) (*GossipData, error) {
+	if data.Codec == "" {
+		return data, nil
+	}
+	codec, ok := GetCodec(data.Codec)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("[gossip] Unknown codec %s", data.Codec))
+	}
+	maxSize, _ := node.sizeLimits()
+	payload, err := codec.Decompress(data.Payload, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	plain := *data
+	plain.Payload = payload
+	plain.Codec = ""
+	return &plain, nil
+}
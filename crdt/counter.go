@@ -0,0 +1,235 @@
+package crdt
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+// counts maps replicas to what they counted. Merging takes the maximum of
+// every replica.
+type counts map[string]uint64
+
+// merge reports whether other had larger counts.
+func (c counts) merge(other map[string]uint64) bool {
+	changed := false
+	for nodeId, count := range other {
+		if count > c[nodeId] {
+			c[nodeId] = count
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (c counts) sum() uint64 {
+	var sum uint64
+	for _, count := range c {
+		sum += count
+	}
+	return sum
+}
+
+// replicaKey identifies the counts of a node since it created the counter.
+// A restarted node counts from zero again, under a new key, so that merging
+// does not take its old count over its new increments.
+func replicaKey(node *gossip.Node, epoch int64) string {
+	return fmt.Sprintf("%s/%d", node.NodeId().String(), epoch)
+}
+
+func (c counts) copy() map[string]uint64 {
+	copied := make(map[string]uint64, len(c))
+	for nodeId, count := range c {
+		copied[nodeId] = count
+	}
+	return copied
+}
+
+// GCounter is a counter that only grows.
+type GCounter struct {
+	*replicator
+	counts   counts
+	epoch    int64
+	onChange func(value uint64)
+	lock     *sync.Mutex
+}
+
+// NewGCounter replicates the counter called name over node.
+func NewGCounter(node *gossip.Node, name string) (*GCounter, error) {
+	c := &GCounter{
+		counts: make(counts),
+		epoch:  time.Now().UnixNano(),
+		lock:   &sync.Mutex{},
+	}
+	r, err := newReplicator(node, name, c)
+	if err != nil {
+		return nil, err
+	}
+	c.replicator = r
+	return c, nil
+}
+
+// OnChange registers fn to be called whenever the value changes, locally or
+// remotely.
+func (c *GCounter) OnChange(fn func(value uint64)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onChange = fn
+}
+
+func (c *GCounter) Value() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts.sum()
+}
+
+func (c *GCounter) Increment(delta uint64) error {
+	if delta == 0 {
+		return nil
+	}
+	replica := replicaKey(c.node, c.epoch)
+	c.lock.Lock()
+	c.counts[replica] += delta
+	count := c.counts[replica]
+	notify := c.notify()
+	c.lock.Unlock()
+	run(notify)
+	return c.publish(&GCounterState{Counts: map[string]uint64{replica: count}})
+}
+
+// notify returns the notification of a change. The caller holds the lock.
+func (c *GCounter) notify() []func() {
+	if c.onChange == nil {
+		return nil
+	}
+	fn, value := c.onChange, c.counts.sum()
+	return []func(){func() { fn(value) }}
+}
+
+func (c *GCounter) snapshot() ([]byte, error) {
+	c.lock.Lock()
+	state := &GCounterState{Counts: c.counts.copy()}
+	c.lock.Unlock()
+	return proto.Marshal(state)
+}
+
+func (c *GCounter) merge(data []byte) ([]func(), error) {
+	state := &GCounterState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.counts.merge(state.Counts) {
+		return nil, nil
+	}
+	return c.notify(), nil
+}
+
+// PNCounter is a counter that can be incremented and decremented. It counts
+// increments and decrements separately.
+type PNCounter struct {
+	*replicator
+	inc      counts
+	dec      counts
+	epoch    int64
+	onChange func(value int64)
+	lock     *sync.Mutex
+}
+
+// NewPNCounter replicates the counter called name over node.
+func NewPNCounter(node *gossip.Node, name string) (*PNCounter, error) {
+	c := &PNCounter{
+		inc:   make(counts),
+		dec:   make(counts),
+		epoch: time.Now().UnixNano(),
+		lock:  &sync.Mutex{},
+	}
+	r, err := newReplicator(node, name, c)
+	if err != nil {
+		return nil, err
+	}
+	c.replicator = r
+	return c, nil
+}
+
+// OnChange registers fn to be called whenever the value changes, locally or
+// remotely.
+func (c *PNCounter) OnChange(fn func(value int64)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onChange = fn
+}
+
+func (c *PNCounter) Value() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.value()
+}
+
+func (c *PNCounter) value() int64 {
+	return int64(c.inc.sum()) - int64(c.dec.sum())
+}
+
+// Increment adds delta, which may be negative.
+func (c *PNCounter) Increment(delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	replica := replicaKey(c.node, c.epoch)
+	state := &PNCounterState{}
+	c.lock.Lock()
+	if delta > 0 {
+		c.inc[replica] += uint64(delta)
+		state.Inc = &GCounterState{Counts: map[string]uint64{replica: c.inc[replica]}}
+	} else {
+		c.dec[replica] += uint64(-delta)
+		state.Dec = &GCounterState{Counts: map[string]uint64{replica: c.dec[replica]}}
+	}
+	notify := c.notify()
+	c.lock.Unlock()
+	run(notify)
+	return c.publish(state)
+}
+
+func (c *PNCounter) Decrement(delta int64) error {
+	return c.Increment(-delta)
+}
+
+// notify returns the notification of a change. The caller holds the lock.
+func (c *PNCounter) notify() []func() {
+	if c.onChange == nil {
+		return nil
+	}
+	fn, value := c.onChange, c.value()
+	return []func(){func() { fn(value) }}
+}
+
+func (c *PNCounter) snapshot() ([]byte, error) {
+	c.lock.Lock()
+	state := &PNCounterState{
+		Inc: &GCounterState{Counts: c.inc.copy()},
+		Dec: &GCounterState{Counts: c.dec.copy()},
+	}
+	c.lock.Unlock()
+	return proto.Marshal(state)
+}
+
+func (c *PNCounter) merge(data []byte) ([]func(), error) {
+	state := &PNCounterState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	before := c.value()
+	c.inc.merge(state.GetInc().GetCounts())
+	c.dec.merge(state.GetDec().GetCounts())
+	if c.value() == before {
+		return nil, nil
+	}
+	return c.notify(), nil
+}
@@ -0,0 +1,134 @@
+// Package crdt replicates conflict-free data types over a gossip node.
+// Updates are gossiped as deltas, which are states themselves, so merging is
+// all replicas ever do. Replicas also exchange their full states with a
+// random neighbor now and then, so that lost deltas and new joiners catch
+// up.
+package crdt
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+const channelPrefix = "crdt/"
+const antiEntropyInterval = 10 * time.Second
+const syncTimeout = 10 * time.Second
+
+// state is implemented by every data type.
+type state interface {
+	// snapshot returns the full state.
+	snapshot() ([]byte, error)
+	// merge merges a state or delta, and returns the change notifications
+	// to run once no lock is held.
+	merge(data []byte) ([]func(), error)
+}
+
+// replicator ties a data type to a node.
+type replicator struct {
+	node    *gossip.Node
+	channel string
+	state   state
+	closed  chan struct{}
+	once    *sync.Once
+}
+
+func newReplicator(node *gossip.Node, name string, s state) (*replicator, error) {
+	r := &replicator{
+		node:    node,
+		channel: channelPrefix + name,
+		state:   s,
+		closed:  make(chan struct{}),
+		once:    &sync.Once{},
+	}
+	if err := node.HandleChannel(r.channel, r.receive); err != nil {
+		return nil, err
+	}
+	node.HandleDirect(r.channel, r.exchange)
+	go r.antiEntropy()
+	return r, nil
+}
+
+// Sync exchanges the full state with a random neighbor, so that both have
+// the updates of the other. Replicas sync in the background as well; a node
+// that restarted catches up on the state it lost that way, while its own
+// updates count from the start.
+func (r *replicator) Sync(ctx context.Context) error {
+	nodeIds := r.node.GetNeighborList().SampleNodeIdWhere(1, func(nodeId gossip.NodeId, info *gossip.PeerInfo) bool {
+		return info != nil && info.Supports(gossip.FeatureDirect)
+	})
+	if len(nodeIds) == 0 {
+		return errors.New("[crdt] No neighbor to sync with")
+	}
+	data, err := r.state.snapshot()
+	if err != nil {
+		return err
+	}
+	res, err := r.node.RequestChannel(ctx, nodeIds[0], r.channel, data)
+	if err != nil {
+		return err
+	}
+	notify, err := r.state.merge(res)
+	run(notify)
+	return err
+}
+
+// Close stops replicating. The node keeps running.
+func (r *replicator) Close() {
+	r.once.Do(func() {
+		close(r.closed)
+		r.node.HandleChannel(r.channel, nil)
+		r.node.HandleDirect(r.channel, nil)
+	})
+}
+
+func (r *replicator) publish(delta proto.Message) error {
+	data, err := proto.Marshal(delta)
+	if err != nil {
+		return err
+	}
+	return r.node.GossipChannel(r.channel, data)
+}
+
+func (r *replicator) receive(from gossip.NodeId, payload []byte) {
+	notify, err := r.state.merge(payload)
+	if err != nil {
+		log.Printf("[crdt] Malformed delta on %s from %s: %s", r.channel, from.String(), err.Error())
+	}
+	run(notify)
+}
+
+// exchange merges the state of a neighbor that syncs, and sends back the
+// own one.
+func (r *replicator) exchange(ctx context.Context, from gossip.NodeId, payload []byte) ([]byte, error) {
+	notify, err := r.state.merge(payload)
+	if err != nil {
+		return nil, err
+	}
+	run(notify)
+	return r.state.snapshot()
+}
+
+func (r *replicator) antiEntropy() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+		r.Sync(ctx)
+		cancel()
+		select {
+		case <-r.closed:
+			return
+		case <-time.After(antiEntropyInterval):
+		}
+	}
+}
+
+func run(notify []func()) {
+	for _, fn := range notify {
+		fn()
+	}
+}
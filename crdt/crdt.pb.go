@@ -0,0 +1,349 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: crdt.proto
+
+package crdt
+
+import (
+	fmt "fmt"
+	math "math"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type LWWEntry struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp            int64    `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	NodeId               string   `protobuf:"bytes,4,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Deleted              bool     `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LWWEntry) Reset()         { *m = LWWEntry{} }
+func (m *LWWEntry) String() string { return proto.CompactTextString(m) }
+func (*LWWEntry) ProtoMessage()    {}
+func (*LWWEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{0}
+}
+
+func (m *LWWEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LWWEntry.Unmarshal(m, b)
+}
+func (m *LWWEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LWWEntry.Marshal(b, m, deterministic)
+}
+func (m *LWWEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LWWEntry.Merge(m, src)
+}
+func (m *LWWEntry) XXX_Size() int {
+	return xxx_messageInfo_LWWEntry.Size(m)
+}
+func (m *LWWEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LWWEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LWWEntry proto.InternalMessageInfo
+
+func (m *LWWEntry) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *LWWEntry) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *LWWEntry) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *LWWEntry) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *LWWEntry) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+type LWWMapState struct {
+	Entries              []*LWWEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *LWWMapState) Reset()         { *m = LWWMapState{} }
+func (m *LWWMapState) String() string { return proto.CompactTextString(m) }
+func (*LWWMapState) ProtoMessage()    {}
+func (*LWWMapState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{1}
+}
+
+func (m *LWWMapState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LWWMapState.Unmarshal(m, b)
+}
+func (m *LWWMapState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LWWMapState.Marshal(b, m, deterministic)
+}
+func (m *LWWMapState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LWWMapState.Merge(m, src)
+}
+func (m *LWWMapState) XXX_Size() int {
+	return xxx_messageInfo_LWWMapState.Size(m)
+}
+func (m *LWWMapState) XXX_DiscardUnknown() {
+	xxx_messageInfo_LWWMapState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LWWMapState proto.InternalMessageInfo
+
+func (m *LWWMapState) GetEntries() []*LWWEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type GCounterState struct {
+	Counts               map[string]uint64 `protobuf:"bytes,1,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *GCounterState) Reset()         { *m = GCounterState{} }
+func (m *GCounterState) String() string { return proto.CompactTextString(m) }
+func (*GCounterState) ProtoMessage()    {}
+func (*GCounterState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{2}
+}
+
+func (m *GCounterState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GCounterState.Unmarshal(m, b)
+}
+func (m *GCounterState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GCounterState.Marshal(b, m, deterministic)
+}
+func (m *GCounterState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GCounterState.Merge(m, src)
+}
+func (m *GCounterState) XXX_Size() int {
+	return xxx_messageInfo_GCounterState.Size(m)
+}
+func (m *GCounterState) XXX_DiscardUnknown() {
+	xxx_messageInfo_GCounterState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GCounterState proto.InternalMessageInfo
+
+func (m *GCounterState) GetCounts() map[string]uint64 {
+	if m != nil {
+		return m.Counts
+	}
+	return nil
+}
+
+type PNCounterState struct {
+	Inc                  *GCounterState `protobuf:"bytes,1,opt,name=inc,proto3" json:"inc,omitempty"`
+	Dec                  *GCounterState `protobuf:"bytes,2,opt,name=dec,proto3" json:"dec,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *PNCounterState) Reset()         { *m = PNCounterState{} }
+func (m *PNCounterState) String() string { return proto.CompactTextString(m) }
+func (*PNCounterState) ProtoMessage()    {}
+func (*PNCounterState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{3}
+}
+
+func (m *PNCounterState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PNCounterState.Unmarshal(m, b)
+}
+func (m *PNCounterState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PNCounterState.Marshal(b, m, deterministic)
+}
+func (m *PNCounterState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PNCounterState.Merge(m, src)
+}
+func (m *PNCounterState) XXX_Size() int {
+	return xxx_messageInfo_PNCounterState.Size(m)
+}
+func (m *PNCounterState) XXX_DiscardUnknown() {
+	xxx_messageInfo_PNCounterState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PNCounterState proto.InternalMessageInfo
+
+func (m *PNCounterState) GetInc() *GCounterState {
+	if m != nil {
+		return m.Inc
+	}
+	return nil
+}
+
+func (m *PNCounterState) GetDec() *GCounterState {
+	if m != nil {
+		return m.Dec
+	}
+	return nil
+}
+
+type ORSetElement struct {
+	Value                string   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Tags                 []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ORSetElement) Reset()         { *m = ORSetElement{} }
+func (m *ORSetElement) String() string { return proto.CompactTextString(m) }
+func (*ORSetElement) ProtoMessage()    {}
+func (*ORSetElement) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{4}
+}
+
+func (m *ORSetElement) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ORSetElement.Unmarshal(m, b)
+}
+func (m *ORSetElement) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ORSetElement.Marshal(b, m, deterministic)
+}
+func (m *ORSetElement) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ORSetElement.Merge(m, src)
+}
+func (m *ORSetElement) XXX_Size() int {
+	return xxx_messageInfo_ORSetElement.Size(m)
+}
+func (m *ORSetElement) XXX_DiscardUnknown() {
+	xxx_messageInfo_ORSetElement.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ORSetElement proto.InternalMessageInfo
+
+func (m *ORSetElement) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *ORSetElement) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+type ORSetState struct {
+	Adds                 []*ORSetElement `protobuf:"bytes,1,rep,name=adds,proto3" json:"adds,omitempty"`
+	Removes              []*ORSetElement `protobuf:"bytes,2,rep,name=removes,proto3" json:"removes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ORSetState) Reset()         { *m = ORSetState{} }
+func (m *ORSetState) String() string { return proto.CompactTextString(m) }
+func (*ORSetState) ProtoMessage()    {}
+func (*ORSetState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c2f7bf98db4e2cd, []int{5}
+}
+
+func (m *ORSetState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ORSetState.Unmarshal(m, b)
+}
+func (m *ORSetState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ORSetState.Marshal(b, m, deterministic)
+}
+func (m *ORSetState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ORSetState.Merge(m, src)
+}
+func (m *ORSetState) XXX_Size() int {
+	return xxx_messageInfo_ORSetState.Size(m)
+}
+func (m *ORSetState) XXX_DiscardUnknown() {
+	xxx_messageInfo_ORSetState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ORSetState proto.InternalMessageInfo
+
+func (m *ORSetState) GetAdds() []*ORSetElement {
+	if m != nil {
+		return m.Adds
+	}
+	return nil
+}
+
+func (m *ORSetState) GetRemoves() []*ORSetElement {
+	if m != nil {
+		return m.Removes
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*LWWEntry)(nil), "crdt.LWWEntry")
+	proto.RegisterType((*LWWMapState)(nil), "crdt.LWWMapState")
+	proto.RegisterType((*GCounterState)(nil), "crdt.GCounterState")
+	proto.RegisterMapType((map[string]uint64)(nil), "crdt.GCounterState.CountsEntry")
+	proto.RegisterType((*PNCounterState)(nil), "crdt.PNCounterState")
+	proto.RegisterType((*ORSetElement)(nil), "crdt.ORSetElement")
+	proto.RegisterType((*ORSetState)(nil), "crdt.ORSetState")
+}
+
+func init() { proto.RegisterFile("crdt.proto", fileDescriptor_0c2f7bf98db4e2cd) }
+
+var fileDescriptor_0c2f7bf98db4e2cd = []byte{
+	// 335 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0xcf, 0x4b, 0xfb, 0x40,
+	0x10, 0xc5, 0xd9, 0x24, 0xfd, 0x35, 0xe9, 0xb7, 0x7c, 0x59, 0x45, 0x16, 0x11, 0x0c, 0x01, 0x65,
+	0x0f, 0xd2, 0x43, 0x3d, 0xb4, 0x7a, 0x95, 0x22, 0x42, 0xfd, 0xc1, 0xf6, 0x90, 0x9b, 0x90, 0x66,
+	0x07, 0x29, 0x36, 0x49, 0x49, 0xa6, 0x85, 0x5e, 0xf5, 0x1f, 0x97, 0xec, 0x36, 0xb4, 0x85, 0xa2,
+	0xb7, 0x79, 0x3b, 0xef, 0xbd, 0x7c, 0x18, 0x02, 0x90, 0x14, 0x9a, 0xfa, 0xcb, 0x22, 0xa7, 0x9c,
+	0x7b, 0xd5, 0x1c, 0x7e, 0x31, 0x68, 0x4f, 0xa2, 0x68, 0x9c, 0x51, 0xb1, 0xe1, 0xff, 0xc1, 0xfd,
+	0xc4, 0x8d, 0x60, 0x01, 0x93, 0x1d, 0x55, 0x8d, 0xfc, 0x14, 0x1a, 0xeb, 0x78, 0xb1, 0x42, 0xe1,
+	0x04, 0x4c, 0x76, 0x95, 0x15, 0xfc, 0x02, 0x3a, 0x34, 0x4f, 0xb1, 0xa4, 0x38, 0x5d, 0x0a, 0x37,
+	0x60, 0xd2, 0x55, 0xbb, 0x07, 0x7e, 0x06, 0xcd, 0x2c, 0xd7, 0xf8, 0xa4, 0x85, 0x67, 0x8a, 0xb6,
+	0x8a, 0x0b, 0x68, 0x69, 0x5c, 0x20, 0xa1, 0x16, 0x8d, 0x80, 0xc9, 0xb6, 0xaa, 0x65, 0x38, 0x04,
+	0x7f, 0x12, 0x45, 0xcf, 0xf1, 0x72, 0x4a, 0x31, 0x21, 0x97, 0xd0, 0xc2, 0x8c, 0x8a, 0x39, 0x96,
+	0x82, 0x05, 0xae, 0xf4, 0x07, 0xbd, 0xbe, 0xe1, 0xae, 0x39, 0x55, 0xbd, 0x0e, 0xbf, 0x19, 0xfc,
+	0x7b, 0x7c, 0xc8, 0x57, 0x19, 0x61, 0x61, 0xb3, 0x43, 0x68, 0x26, 0x95, 0xae, 0xa3, 0x97, 0x36,
+	0x7a, 0x60, 0xea, 0x1b, 0x51, 0xda, 0xae, 0xad, 0xfd, 0xfc, 0x0e, 0xfc, 0xbd, 0xe7, 0xbf, 0x4e,
+	0xe1, 0x6d, 0x4f, 0x71, 0xef, 0x8c, 0x58, 0xf8, 0x0e, 0xbd, 0xb7, 0x97, 0x03, 0x8a, 0x2b, 0x70,
+	0xe7, 0x59, 0x62, 0xd2, 0xfe, 0xe0, 0xe4, 0x08, 0x82, 0xaa, 0xf6, 0x95, 0x4d, 0x63, 0x22, 0x9c,
+	0x5f, 0x6c, 0x1a, 0x93, 0x70, 0x04, 0xdd, 0x57, 0x35, 0x45, 0x1a, 0x2f, 0x30, 0xc5, 0x8c, 0x76,
+	0x24, 0x96, 0xce, 0x0a, 0xce, 0xc1, 0xa3, 0xf8, 0xa3, 0x14, 0x4e, 0xe0, 0xca, 0x8e, 0x32, 0x73,
+	0x38, 0x03, 0x30, 0x49, 0x4b, 0x75, 0x0d, 0x5e, 0xac, 0x75, 0x7d, 0x19, 0x6e, 0xbf, 0xb7, 0xdf,
+	0xac, 0xcc, 0x9e, 0xdf, 0x40, 0xab, 0xc0, 0x34, 0x5f, 0xa3, 0x2d, 0x3b, 0x6e, 0xad, 0x2d, 0xb3,
+	0xa6, 0xf9, 0x9d, 0x6e, 0x7f, 0x06, 0x00, 0x58, 0xe3, 0x0b, 0x04, 0x5c, 0x02, 0x00, 0x00,
+}
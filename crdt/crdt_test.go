@@ -0,0 +1,235 @@
+package crdt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zllai/gossip"
+)
+
+// transfer merges the full state of from into to, like anti-entropy does.
+func transfer(t *testing.T, from state, to state) {
+	data, err := from.snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notify, err := to.merge(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(notify)
+}
+
+func TestLWWMap(t *testing.T) {
+	a, err := NewLWWMap(gossip.New(gossip.NewNodeId("127.0.0.1:1"), "test topic"), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewLWWMap(gossip.New(gossip.NewNodeId("127.0.0.1:2"), "test topic"), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var changes []string
+	b.OnChange(func(key string, value []byte, deleted bool) {
+		changes = append(changes, key)
+	})
+
+	a.Set("x", []byte("1"))
+	a.Set("y", []byte("1"))
+	b.Set("x", []byte("2"))
+	a.Delete("y")
+	transfer(t, a, b)
+	transfer(t, b, a)
+	for _, m := range []*LWWMap{a, b} {
+		if value, _ := m.Get("x"); string(value) != "2" {
+			t.Errorf("unexpected value %q", value)
+		}
+		if keys := m.Keys(); !reflect.DeepEqual(keys, []string{"x"}) {
+			t.Errorf("unexpected keys %v", keys)
+		}
+	}
+	// the deleted key was never set on b
+	if !reflect.DeepEqual(changes, []string{"x"}) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	var g [2]*GCounter
+	var pn [2]*PNCounter
+	for i := range g {
+		node := gossip.New(gossip.NewNodeId(fmt.Sprintf("127.0.0.1:%d", i+1)), "test topic")
+		var err error
+		if g[i], err = NewGCounter(node, "requests"); err != nil {
+			t.Fatal(err)
+		}
+		defer g[i].Close()
+		if pn[i], err = NewPNCounter(node, "connections"); err != nil {
+			t.Fatal(err)
+		}
+		defer pn[i].Close()
+	}
+	g[0].Increment(3)
+	g[1].Increment(4)
+	pn[0].Increment(5)
+	pn[1].Decrement(2)
+	for round := 0; round < 2; round++ {
+		transfer(t, g[0], g[1])
+		transfer(t, g[1], g[0])
+		transfer(t, pn[0], pn[1])
+		transfer(t, pn[1], pn[0])
+	}
+	for i := range g {
+		if g[i].Value() != 7 || pn[i].Value() != 3 {
+			t.Errorf("unexpected values %d and %d", g[i].Value(), pn[i].Value())
+		}
+	}
+}
+
+func TestCountersRestart(t *testing.T) {
+	peer := gossip.New(gossip.NewNodeId("127.0.0.1:1"), "test topic")
+	g, err := NewGCounter(peer, "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	pn, err := NewPNCounter(peer, "connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pn.Close()
+
+	nodeId := gossip.NewNodeId("127.0.0.1:2")
+	before, err := NewGCounter(gossip.New(nodeId, "test topic"), "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before.Increment(5)
+	beforePN, err := NewPNCounter(gossip.New(nodeId, "test topic"), "connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beforePN.Increment(5)
+	transfer(t, before, g)
+	transfer(t, beforePN, pn)
+	before.Close()
+	beforePN.Close()
+
+	// the node restarts and counts before syncing
+	after, err := NewGCounter(gossip.New(nodeId, "test topic"), "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer after.Close()
+	after.Increment(2)
+	afterPN, err := NewPNCounter(gossip.New(nodeId, "test topic"), "connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer afterPN.Close()
+	afterPN.Increment(2)
+	transfer(t, after, g)
+	transfer(t, afterPN, pn)
+	transfer(t, g, after)
+	transfer(t, pn, afterPN)
+	if g.Value() != 7 || after.Value() != 7 || pn.Value() != 7 || afterPN.Value() != 7 {
+		t.Errorf("increments after the restart lost: %d, %d, %d, %d", g.Value(), after.Value(), pn.Value(), afterPN.Value())
+	}
+}
+
+func TestORSet(t *testing.T) {
+	a, err := NewORSet(gossip.New(gossip.NewNodeId("127.0.0.1:1"), "test topic"), "members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewORSet(gossip.New(gossip.NewNodeId("127.0.0.1:2"), "test topic"), "members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	a.Add("x")
+	a.Add("y")
+	transfer(t, a, b)
+	// a removes x while b adds it again, and the add wins
+	a.Remove("x")
+	b.Add("x")
+	b.Remove("y")
+	transfer(t, a, b)
+	transfer(t, b, a)
+	for _, s := range []*ORSet{a, b} {
+		if values := s.Values(); !reflect.DeepEqual(values, []string{"x"}) {
+			t.Errorf("unexpected values %v", values)
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	bootNode := gossip.New(gossip.NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	a, err := NewLWWMap(bootNode, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err := a.Set("x", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new joiner catches up through anti-entropy
+	node := gossip.New(gossip.NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	b, err := NewLWWMap(node, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	node.Join([]gossip.NodeId{bootNode.NodeId()})
+	waitFor(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		return b.Sync(ctx) == nil
+	})
+	if value, _ := b.Get("x"); string(value) != "1" {
+		t.Fatalf("unexpected value %q", value)
+	}
+
+	// and then receives deltas
+	changed := make(chan string, 1)
+	b.OnChange(func(key string, value []byte, deleted bool) {
+		changed <- string(value)
+	})
+	waitFor(t, func() bool {
+		return bootNode.PeerSupports(node.NodeId(), gossip.FeatureChannels)
+	})
+	a.Set("x", []byte("2"))
+	select {
+	case value := <-changed:
+		if value != "2" {
+			t.Errorf("unexpected value %q", value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("delta not received")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
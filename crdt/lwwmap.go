@@ -0,0 +1,138 @@
+package crdt
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+// LWWMap maps keys to values. Concurrent writes to a key are resolved by
+// their timestamps: the last writer wins, and ties go to the larger node
+// id. Deleted keys leave a tombstone behind.
+type LWWMap struct {
+	*replicator
+	entries  map[string]*LWWEntry
+	clock    int64
+	onChange func(key string, value []byte, deleted bool)
+	lock     *sync.Mutex
+}
+
+// NewLWWMap replicates the map called name over node.
+func NewLWWMap(node *gossip.Node, name string) (*LWWMap, error) {
+	m := &LWWMap{
+		entries: make(map[string]*LWWEntry),
+		lock:    &sync.Mutex{},
+	}
+	r, err := newReplicator(node, name, m)
+	if err != nil {
+		return nil, err
+	}
+	m.replicator = r
+	return m, nil
+}
+
+// OnChange registers fn to be called whenever the value of a key changes,
+// locally or remotely.
+func (m *LWWMap) OnChange(fn func(key string, value []byte, deleted bool)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.onChange = fn
+}
+
+func (m *LWWMap) Get(key string) ([]byte, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	entry, ok := m.entries[key]
+	if !ok || entry.Deleted {
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+// Keys returns the keys that are set, in order.
+func (m *LWWMap) Keys() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	keys := make([]string, 0, len(m.entries))
+	for key, entry := range m.entries {
+		if !entry.Deleted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *LWWMap) Set(key string, value []byte) error {
+	return m.write(key, value, false)
+}
+
+func (m *LWWMap) Delete(key string) error {
+	return m.write(key, nil, true)
+}
+
+func (m *LWWMap) write(key string, value []byte, deleted bool) error {
+	m.lock.Lock()
+	// the clock stays ahead of every timestamp seen, so that writes win over
+	// what the node has observed
+	timestamp := time.Now().UnixNano()
+	if timestamp <= m.clock {
+		timestamp = m.clock + 1
+	}
+	entry := &LWWEntry{
+		Key:       key,
+		Value:     value,
+		Timestamp: timestamp,
+		NodeId:    m.node.NodeId().String(),
+		Deleted:   deleted,
+	}
+	notify := m.apply(entry)
+	m.lock.Unlock()
+	run(notify)
+	return m.publish(&LWWMapState{Entries: []*LWWEntry{entry}})
+}
+
+// apply keeps entry if it wins over the current one. The caller holds the
+// lock.
+func (m *LWWMap) apply(entry *LWWEntry) []func() {
+	if entry.Timestamp > m.clock {
+		m.clock = entry.Timestamp
+	}
+	current, ok := m.entries[entry.Key]
+	if ok && (current.Timestamp > entry.Timestamp || current.Timestamp == entry.Timestamp && current.NodeId >= entry.NodeId) {
+		return nil
+	}
+	m.entries[entry.Key] = entry
+	if m.onChange == nil || entry.Deleted && (!ok || current.Deleted) {
+		return nil
+	}
+	fn := m.onChange
+	return []func(){func() { fn(entry.Key, entry.Value, entry.Deleted) }}
+}
+
+func (m *LWWMap) snapshot() ([]byte, error) {
+	m.lock.Lock()
+	state := &LWWMapState{Entries: make([]*LWWEntry, 0, len(m.entries))}
+	for _, entry := range m.entries {
+		state.Entries = append(state.Entries, entry)
+	}
+	m.lock.Unlock()
+	return proto.Marshal(state)
+}
+
+func (m *LWWMap) merge(data []byte) ([]func(), error) {
+	state := &LWWMapState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var notify []func()
+	for _, entry := range state.Entries {
+		notify = append(notify, m.apply(entry)...)
+	}
+	return notify, nil
+}
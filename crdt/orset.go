@@ -0,0 +1,174 @@
+package crdt
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+// ORSet is an observed-remove set. Every add tags the value uniquely, and a
+// remove only removes the tags it observed, so an add wins over a
+// concurrent remove. The tags of removed values stay behind.
+type ORSet struct {
+	*replicator
+	adds     map[string]map[string]bool
+	removes  map[string]map[string]bool
+	epoch    int64
+	seq      uint64
+	onChange func(value string, present bool)
+	lock     *sync.Mutex
+}
+
+// NewORSet replicates the set called name over node.
+func NewORSet(node *gossip.Node, name string) (*ORSet, error) {
+	s := &ORSet{
+		adds:    make(map[string]map[string]bool),
+		removes: make(map[string]map[string]bool),
+		// tags stay unique across restarts
+		epoch: time.Now().UnixNano(),
+		lock:  &sync.Mutex{},
+	}
+	r, err := newReplicator(node, name, s)
+	if err != nil {
+		return nil, err
+	}
+	s.replicator = r
+	return s, nil
+}
+
+// OnChange registers fn to be called whenever a value is added to or
+// removed from the set, locally or remotely.
+func (s *ORSet) OnChange(fn func(value string, present bool)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onChange = fn
+}
+
+func (s *ORSet) Contains(value string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.contains(value)
+}
+
+func (s *ORSet) contains(value string) bool {
+	for tag := range s.adds[value] {
+		if !s.removes[value][tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values in the set, in order.
+func (s *ORSet) Values() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	values := make([]string, 0, len(s.adds))
+	for value := range s.adds {
+		if s.contains(value) {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (s *ORSet) Add(value string) error {
+	s.lock.Lock()
+	s.seq++
+	tag := fmt.Sprintf("%s/%d/%d", s.node.NodeId().String(), s.epoch, s.seq)
+	delta := &ORSetState{Adds: []*ORSetElement{{Value: value, Tags: []string{tag}}}}
+	notify := s.apply(delta)
+	s.lock.Unlock()
+	run(notify)
+	return s.publish(delta)
+}
+
+// Remove removes value, unless it is not in the set.
+func (s *ORSet) Remove(value string) error {
+	s.lock.Lock()
+	var tags []string
+	for tag := range s.adds[value] {
+		if !s.removes[value][tag] {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		s.lock.Unlock()
+		return nil
+	}
+	delta := &ORSetState{Removes: []*ORSetElement{{Value: value, Tags: tags}}}
+	notify := s.apply(delta)
+	s.lock.Unlock()
+	run(notify)
+	return s.publish(delta)
+}
+
+// apply merges state and returns the notifications of the values that were
+// added or removed. The caller holds the lock.
+func (s *ORSet) apply(state *ORSetState) []func() {
+	before := make(map[string]bool)
+	for _, elements := range [][]*ORSetElement{state.Adds, state.Removes} {
+		for _, element := range elements {
+			before[element.Value] = s.contains(element.Value)
+		}
+	}
+	addTags(s.adds, state.Adds)
+	addTags(s.removes, state.Removes)
+	if s.onChange == nil {
+		return nil
+	}
+	var notify []func()
+	fn := s.onChange
+	for value, present := range before {
+		if now := s.contains(value); now != present {
+			value := value
+			notify = append(notify, func() { fn(value, now) })
+		}
+	}
+	return notify
+}
+
+func addTags(tags map[string]map[string]bool, elements []*ORSetElement) {
+	for _, element := range elements {
+		if tags[element.Value] == nil {
+			tags[element.Value] = make(map[string]bool)
+		}
+		for _, tag := range element.Tags {
+			tags[element.Value][tag] = true
+		}
+	}
+}
+
+func elements(tags map[string]map[string]bool) []*ORSetElement {
+	elements := make([]*ORSetElement, 0, len(tags))
+	for value, set := range tags {
+		element := &ORSetElement{Value: value}
+		for tag := range set {
+			element.Tags = append(element.Tags, tag)
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+func (s *ORSet) snapshot() ([]byte, error) {
+	s.lock.Lock()
+	state := &ORSetState{Adds: elements(s.adds), Removes: elements(s.removes)}
+	s.lock.Unlock()
+	return proto.Marshal(state)
+}
+
+func (s *ORSet) merge(data []byte) ([]func(), error) {
+	state := &ORSetState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.apply(state), nil
+}
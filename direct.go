@@ -0,0 +1,267 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const FeatureDirect = "direct"
+
+const defaultDirectTTL = 6
+const directRelayFanout = 3
+const directTimeout = 5 * time.Second
+const directHandlerTimeout = 30 * time.Second
+
+// DirectHandler handles a message sent to the node. For requests, what it
+// returns is sent back as the response; otherwise it is ignored.
+type DirectHandler func(ctx context.Context, from NodeId, payload []byte) ([]byte, error)
+
+// HandleDirect registers the handler for direct messages on channel. SendTo
+// and Request use the default channel "". A nil handler removes it.
+func (node *Node) HandleDirect(channel string, handler DirectHandler) {
+	node.directs.lock.Lock()
+	defer node.directs.lock.Unlock()
+	if handler == nil {
+		delete(node.directs.handlers, channel)
+		return
+	}
+	node.directs.handlers[channel] = handler
+}
+
+// SendTo sends payload to nodeId. It is sent directly if nodeId is a
+// neighbor, and relayed through neighbors otherwise, in which case it only
+// reports whether the first hop took it.
+func (node *Node) SendTo(ctx context.Context, nodeId NodeId, payload []byte) error {
+	return node.sendDirect(ctx, nodeId, "", payload, 0)
+}
+
+// Request sends payload to nodeId like SendTo, and waits for the response
+// of its handler.
+func (node *Node) Request(ctx context.Context, nodeId NodeId, payload []byte) ([]byte, error) {
+	return node.request(ctx, nodeId, "", payload)
+}
+
+func (node *Node) request(ctx context.Context, nodeId NodeId, channel string, payload []byte) ([]byte, error) {
+	requestId, responses := node.directs.addRequest(nodeId)
+	defer node.directs.doneRequest(requestId)
+	if err := node.sendDirect(ctx, nodeId, channel, payload, requestId); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-responses:
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		return res.Payload, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (node *Node) sendDirect(ctx context.Context, nodeId NodeId, channel string, payload []byte, requestId uint64) error {
+	if err := node.checkMessageSize(len(payload)); err != nil {
+		return err
+	}
+	msg := &DirectMsg{
+		Topic:     node.topic,
+		From:      node.NodeId().String(),
+		To:        nodeId.String(),
+		Channel:   channel,
+		Payload:   payload,
+		Nonce:     rand.Uint64(),
+		Ttl:       node.directTTL(),
+		RequestId: requestId,
+	}
+	return node.forwardDirect(ctx, msg)
+}
+
+// forwardDirect routes a message the node sent itself, and turns the outcome
+// into an error.
+func (node *Node) forwardDirect(ctx context.Context, msg *DirectMsg) error {
+	node.directs.filter.Check(directKey(msg))
+	if msg.To == node.NodeId().String() {
+		return errors.New("[gossip] Cannot send a direct message to the node itself")
+	}
+	switch node.routeDirect(ctx, msg) {
+	case DirectRes_UNREACHABLE:
+		return errors.New(fmt.Sprintf("[gossip] Node %s is unreachable", msg.To))
+	case DirectRes_NO_HANDLER:
+		return errors.New(fmt.Sprintf("[gossip] Node %s does not handle channel %q", msg.To, msg.Channel))
+	}
+	return nil
+}
+
+func (node *Node) Direct(ctx context.Context, msg *DirectMsg) (*DirectRes, error) {
+	if msg.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if err := node.checkMessageSize(len(msg.Payload)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "[From %s] %s", node.NodeId().String(), ReasonTooLarge)
+	}
+	// relayed messages may arrive more than once
+	if !node.directs.filter.Check(directKey(msg)) {
+		return &DirectRes{Status: DirectRes_RELAYED}, nil
+	}
+	if msg.To == node.NodeId().String() {
+		return &DirectRes{Status: node.receiveDirect(msg)}, nil
+	}
+	return &DirectRes{Status: node.routeDirect(ctx, msg)}, nil
+}
+
+func directKey(msg *DirectMsg) string {
+	return msg.From + "/" + strconv.FormatUint(msg.Nonce, 10)
+}
+
+// routeDirect sends msg to its destination if that is a neighbor, and to
+// some other neighbors in the background otherwise.
+func (node *Node) routeDirect(ctx context.Context, msg *DirectMsg) DirectRes_Status {
+	to := NewNodeId(msg.To)
+	if conn, err := node.neighbors.GetConn(to); err == nil && node.PeerSupports(to, FeatureDirect) {
+		ctx, cancel := context.WithTimeout(ctx, directTimeout)
+		defer cancel()
+		res, err := NewGossipClient(conn).Direct(ctx, msg)
+		if err == nil {
+			return res.Status
+		}
+		log.Printf("[gossip] Cannot send direct message to node %s: %s", msg.To, err.Error())
+	}
+	if msg.Ttl == 0 {
+		return DirectRes_UNREACHABLE
+	}
+	relayed := *msg
+	relayed.Ttl--
+	from := NewNodeId(msg.From)
+	relays := node.neighbors.SampleNodeIdWhere(directRelayFanout, func(nodeId NodeId, info *PeerInfo) bool {
+		return nodeId != from && nodeId != to && info != nil && info.Supports(FeatureDirect)
+	})
+	if len(relays) == 0 {
+		return DirectRes_UNREACHABLE
+	}
+	for _, nodeId := range relays {
+		go func(nodeId NodeId) {
+			conn, err := node.neighbors.GetConn(nodeId)
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), directTimeout)
+			defer cancel()
+			if _, err := NewGossipClient(conn).Direct(ctx, &relayed); err != nil {
+				log.Printf("[gossip] Cannot relay direct message to node %s: %s", nodeId.String(), err.Error())
+			}
+		}(nodeId)
+	}
+	return DirectRes_RELAYED
+}
+
+// receiveDirect hands a message for the node to its handler, or to the
+// request waiting for it.
+func (node *Node) receiveDirect(msg *DirectMsg) DirectRes_Status {
+	if msg.Response {
+		node.directs.respond(msg)
+		return DirectRes_DELIVERED
+	}
+	node.directs.lock.Lock()
+	handler, ok := node.directs.handlers[msg.Channel]
+	node.directs.lock.Unlock()
+	if !ok {
+		if msg.RequestId != 0 {
+			go node.respondDirect(msg, nil, errors.New(fmt.Sprintf("no handler for channel %q", msg.Channel)))
+		}
+		return DirectRes_NO_HANDLER
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), directHandlerTimeout)
+		defer cancel()
+		res, err := handler(ctx, NewNodeId(msg.From), msg.Payload)
+		if msg.RequestId != 0 {
+			node.respondDirect(msg, res, err)
+		}
+	}()
+	return DirectRes_DELIVERED
+}
+
+func (node *Node) respondDirect(msg *DirectMsg, payload []byte, err error) {
+	res := &DirectMsg{
+		Topic:     node.topic,
+		From:      node.NodeId().String(),
+		To:        msg.From,
+		Channel:   msg.Channel,
+		Payload:   payload,
+		Nonce:     rand.Uint64(),
+		Ttl:       node.directTTL(),
+		RequestId: msg.RequestId,
+		Response:  true,
+	}
+	if err != nil {
+		res.Payload = nil
+		res.Error = err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), directTimeout)
+	defer cancel()
+	if err := node.forwardDirect(ctx, res); err != nil {
+		log.Printf("[gossip] Cannot respond to node %s: %s", msg.From, err.Error())
+	}
+}
+
+type pendingRequest struct {
+	to        NodeId
+	responses chan *DirectMsg
+}
+
+// directs holds the direct message handlers and the requests waiting for
+// their responses.
+type directs struct {
+	handlers map[string]DirectHandler
+	requests map[uint64]*pendingRequest
+	filter   *Filter
+	lock     *sync.Mutex
+}
+
+func newDirects() *directs {
+	return &directs{
+		handlers: make(map[string]DirectHandler),
+		requests: make(map[uint64]*pendingRequest),
+		filter:   NewFilter(60),
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (d *directs) addRequest(to NodeId) (uint64, chan *DirectMsg) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	requestId := rand.Uint64()
+	for _, ok := d.requests[requestId]; ok || requestId == 0; _, ok = d.requests[requestId] {
+		requestId = rand.Uint64()
+	}
+	responses := make(chan *DirectMsg, 1)
+	d.requests[requestId] = &pendingRequest{to, responses}
+	return requestId, responses
+}
+
+func (d *directs) doneRequest(requestId uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.requests, requestId)
+}
+
+func (d *directs) respond(msg *DirectMsg) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	request, ok := d.requests[msg.RequestId]
+	if !ok || request.to.String() != msg.From {
+		return
+	}
+	select {
+	case request.responses <- msg:
+	default:
+	}
+}
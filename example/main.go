@@ -32,7 +32,9 @@ func main() {
 
 	input, _, err := reader.ReadLine()
 	for err == nil {
-		node.Gossip(input)
+		if err := node.Gossip(input); err != nil {
+			fmt.Println(err)
+		}
 		input, _, err = reader.ReadLine()
 	}
 
@@ -46,3 +46,13 @@ func (filter *Filter) print() {
 		fmt.Printf("%s\t%d\n", k, v)
 	}
 }
+
+// Has reports whether msgHash was seen within the truncate period, without
+// recording it.
+func (filter *Filter) Has(msgHash string) bool {
+	current := time.Now().Unix()
+	filter.lock.Lock()
+	defer filter.lock.Unlock()
+	recvTime, ok := filter.msgRecord[msgHash]
+	return ok && current-recvTime < filter.truncatePeriod
+}
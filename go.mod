@@ -3,7 +3,6 @@ module github.com/zllai/gossip
 go 1.12
 
 require (
-	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
 	github.com/golang/protobuf v1.3.2
 	google.golang.org/grpc v1.22.1
 )
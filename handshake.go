@@ -0,0 +1,193 @@
+package gossip
+
+import (
+	"context"
+	"log"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ProtocolVersion is the wire protocol spoken by this node, and
+// MinProtocolVersion the oldest one it still talks to. Peers from before the
+// handshake are version 0.
+const ProtocolVersion = 1
+const MinProtocolVersion = 0
+
+const handshakeTimeout = 5 * time.Second
+
+const FeatureCompression = "compression"
+
+// supportedFeatures are the features this version implements and advertises
+// by default.
+var supportedFeatures = []string{FeatureChunking, FeatureLazyPull, FeatureCompression, FeatureDirect, FeatureChannels, FeatureSelectors, FeatureSizeEstimation, FeatureAggregation, FeatureMerkleSync}
+
+type PeerInfo struct {
+	Version  uint32
+	Features map[string]bool
+	Metadata map[string]string
+}
+
+// Supports reports whether both the node and the peer have the feature.
+func (info *PeerInfo) Supports(feature string) bool {
+	return info.Features[feature]
+}
+
+// SetFeatures sets the features the node advertises to its peers on top of
+// the ones it implements. Each peer pair uses the features both of them
+// advertise.
+func (node *Node) SetFeatures(features []string) {
+	node.setFeatures(append(defaultFeatures(), features...))
+}
+
+// defaultFeatures are the features of a node the application has not set
+// any for.
+func defaultFeatures() []string {
+	return append(codecFeatures(), supportedFeatures...)
+}
+
+// setFeatures replaces the features the node advertises, which lets tests
+// pose as peers that lack some of them.
+func (node *Node) setFeatures(features []string) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.features = make(map[string]bool)
+	for i := range features {
+		node.features[features[i]] = true
+	}
+}
+
+func (node *Node) Features() []string {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	features := make([]string, 0, len(node.features))
+	for feature := range node.features {
+		features = append(features, feature)
+	}
+	return features
+}
+
+// SetMetadata sets the key/value pairs the node publishes about itself,
+// such as its role or zone. They are sent along with handshakes and peer
+// exchange, and gossiped when they change. Keep them small.
+func (node *Node) SetMetadata(metadata map[string]string) {
+	node.lock.Lock()
+	node.metadata = copyEntries(metadata)
+	node.metadataVersion = node.nextMetadataVersion()
+	node.lock.Unlock()
+	if node.neighbors.Len() > 0 {
+		node.announceMetadata()
+	}
+}
+
+// PeerSupports reports whether the node and a neighbor negotiated the
+// feature. Peers that have not completed a handshake support nothing.
+func (node *Node) PeerSupports(nodeId NodeId, feature string) bool {
+	info, ok := node.neighbors.GetPeerInfo(nodeId)
+	return ok && info.Supports(feature)
+}
+
+func compatible(version uint32, minVersion uint32) bool {
+	return version >= MinProtocolVersion && ProtocolVersion >= minVersion
+}
+
+func (node *Node) negotiate(version uint32, features []string, metadata map[string]string) *PeerInfo {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	info := &PeerInfo{
+		Version:  version,
+		Features: make(map[string]bool),
+		Metadata: make(map[string]string),
+	}
+	for i := range features {
+		if node.features[features[i]] {
+			info.Features[features[i]] = true
+		}
+	}
+	for k, v := range metadata {
+		info.Metadata[k] = v
+	}
+	return info
+}
+
+func (node *Node) handshakeReq() *HandshakeReq {
+	features := node.Features()
+	metadata := node.ownMetadata()
+	return &HandshakeReq{
+		Topic:           node.topic,
+		NodeId:          metadata.NodeId,
+		Version:         ProtocolVersion,
+		MinVersion:      MinProtocolVersion,
+		Features:        features,
+		Metadata:        metadata.Entries,
+		MetadataVersion: metadata.Version,
+	}
+}
+
+func (node *Node) Handshake(ctx context.Context, req *HandshakeReq) (*HandshakeRes, error) {
+	if req.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if !compatible(req.Version, req.MinVersion) {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] protocol version %d is not supported", node.NodeId().String(), req.Version)
+	}
+	nodeId := NewNodeId(req.NodeId)
+	if node.banned(nodeId, caller(ctx, nodeId)) {
+		return nil, status.Errorf(codes.PermissionDenied, "[From %s] %s", node.NodeId().String(), ReasonBanned)
+	}
+	node.neighbors.Update(nodeId)
+	node.learnMetadata(&NodeMetadata{NodeId: req.NodeId, Version: req.MetadataVersion, Entries: req.Metadata})
+	node.neighbors.SetPeerInfo(nodeId, node.negotiate(req.Version, req.Features, req.Metadata))
+
+	own := node.handshakeReq()
+	res := &HandshakeRes{
+		Topic:           own.Topic,
+		NodeId:          own.NodeId,
+		Version:         own.Version,
+		MinVersion:      own.MinVersion,
+		Features:        own.Features,
+		Metadata:        own.Metadata,
+		MetadataVersion: own.MetadataVersion,
+	}
+	return res, nil
+}
+
+func (node *Node) handshake(nodeId NodeId) {
+	conn, err := node.neighbors.GetConn(nodeId)
+	if err != nil {
+		log.Printf("[gossip] Connection to %s is closed", nodeId.String())
+		return
+	}
+	client := NewGossipClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	res, err := client.Handshake(ctx, node.handshakeReq())
+	if err != nil {
+		switch status.Convert(err).Code() {
+		case codes.Unimplemented:
+			node.neighbors.SetPeerInfo(nodeId, node.negotiate(0, nil, nil))
+		case codes.FailedPrecondition:
+			log.Printf("[gossip] Node %s is incompatible: %s", nodeId.String(), err.Error())
+			node.neighbors.Remove(nodeId)
+		default:
+			log.Printf("[gossip] Cannot handshake with node %s: %s", nodeId.String(), err.Error())
+		}
+		return
+	}
+	if !compatible(res.Version, res.MinVersion) {
+		log.Printf("[gossip] Node %s speaks unsupported protocol version %d", nodeId.String(), res.Version)
+		node.neighbors.Remove(nodeId)
+		return
+	}
+	node.learnMetadata(&NodeMetadata{NodeId: nodeId.String(), Version: res.MetadataVersion, Entries: res.Metadata})
+	node.neighbors.SetPeerInfo(nodeId, node.negotiate(res.Version, res.Features, res.Metadata))
+}
+
+// addPeer adds nodeId to the neighbor list and handshakes with it if it is
+// new.
+func (node *Node) addPeer(nodeId NodeId) {
+	if node.neighbors.Update(nodeId) {
+		go node.handshake(nodeId)
+	}
+}
@@ -0,0 +1,171 @@
+package gossip
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const fetchTimeout = 10 * time.Second
+const maxPendingFetches = 1024
+
+const FeatureLazyPull = "lazy-pull"
+
+// SetLazyThreshold makes the node announce messages of topic whose payload
+// is at least size bytes, instead of pushing them. Peers that miss such a
+// message pull it from one of the announcers. Zero disables lazy mode.
+// Messages are only announced to peers that negotiated FeatureLazyPull, and
+// fragments of chunked messages are always pushed.
+func (node *Node) SetLazyThreshold(topic string, size int) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.lazyThresholds[topic] = size
+}
+
+func (node *Node) isLazy(data *GossipData) bool {
+	if data.Fragment != nil {
+		return false
+	}
+	node.lock.Lock()
+	threshold := node.lazyThresholds[data.Topic]
+	node.lock.Unlock()
+	return threshold > 0 && len(data.Payload) >= threshold
+}
+
+// announcement returns a copy of data without its payload.
+func announcement(data *GossipData) *GossipData {
+	return &GossipData{
+		Topic:    data.Topic,
+		NodeId:   data.NodeId,
+		Nonce:    data.Nonce,
+		Sender:   data.Sender,
+		Announce: true,
+		MsgId:    data.Hash(),
+		Size:     uint64(len(data.Payload)),
+	}
+}
+
+func (node *Node) FetchData(ctx context.Context, req *FetchReq) (*GossipData, error) {
+	if req.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if req.MsgId == "" {
+		data, ok := node.cache.GetSeq(req.Origin, req.Epoch, req.Seq)
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "[From %s] message %d of %s is not cached", node.NodeId().String(), req.Seq, req.Origin)
+		}
+		return data, nil
+	}
+	data, ok := node.cache.Get(req.MsgId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "[From %s] message %s is not cached", node.NodeId().String(), req.MsgId)
+	}
+	return data, nil
+}
+
+// announced handles an announcement by pulling the message unless it is
+// known already.
+func (node *Node) announced(data *GossipData) *SendDataRes {
+	if node.msgFilter.Has(data.MsgId) {
+		node.score(data.From(), ScoreDuplicate)
+		return &SendDataRes{Status: SendDataRes_DUPLICATE}
+	}
+	if node.fetches.add(data.MsgId, data.From()) {
+		go node.fetch(data.MsgId)
+	}
+	return &SendDataRes{Status: SendDataRes_NEW}
+}
+
+// fetch pulls an announced message from its announcers in turn, until one
+// of them returns it.
+func (node *Node) fetch(msgId string) {
+	defer node.fetches.done(msgId)
+	req := &FetchReq{
+		Topic: node.topic,
+		MsgId: msgId,
+	}
+	for {
+		announcer, ok := node.fetches.next(msgId)
+		if !ok {
+			log.Printf("[gossip] Cannot fetch message %s from any announcer", msgId)
+			return
+		}
+		if node.msgFilter.Has(msgId) {
+			return
+		}
+		conn, err := node.neighbors.GetConn(announcer)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		data, err := NewGossipClient(conn).FetchData(ctx, req)
+		cancel()
+		if err != nil {
+			log.Printf("[gossip] Cannot fetch message %s from node %s: %s", msgId, announcer.String(), err.Error())
+			node.score(announcer, ScoreRPCFailure)
+			continue
+		}
+		if data.Hash() != msgId || node.checkSize(data) != "" {
+			log.Printf("[gossip] Node %s returned the wrong message for %s", announcer.String(), msgId)
+			node.score(announcer, ScoreInvalidMessage)
+			continue
+		}
+		data.Sender = announcer.String()
+		node.receive(data)
+		return
+	}
+}
+
+type pendingFetch struct {
+	announcers []NodeId
+	next       int
+}
+
+type fetches struct {
+	pending map[string]*pendingFetch
+	lock    *sync.Mutex
+}
+
+func newFetches() *fetches {
+	return &fetches{
+		pending: make(map[string]*pendingFetch),
+		lock:    &sync.Mutex{},
+	}
+}
+
+// add records an announcer of msgId and reports whether a fetch has to be
+// started for it.
+func (f *fetches) add(msgId string, announcer NodeId) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if pending, ok := f.pending[msgId]; ok {
+		pending.announcers = append(pending.announcers, announcer)
+		return false
+	}
+	if len(f.pending) >= maxPendingFetches {
+		return false
+	}
+	f.pending[msgId] = &pendingFetch{announcers: []NodeId{announcer}}
+	return true
+}
+
+func (f *fetches) next(msgId string) (NodeId, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	pending, ok := f.pending[msgId]
+	if !ok || pending.next >= len(pending.announcers) {
+		return "", false
+	}
+	pending.next++
+	return pending.announcers[pending.next-1], true
+}
+
+func (f *fetches) done(msgId string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.pending, msgId)
+}
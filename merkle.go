@@ -0,0 +1,389 @@
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const FeatureMerkleSync = "merkle-sync"
+
+// keys are bucketed by the first merkleDepth hex digits of their hash, so
+// that the tree has a fanout of 16 and 16^merkleDepth leaves
+const merkleDepth = 4
+const merkleFanout = 16
+const merkleBatch = 256
+const merkleSyncInterval = 30 * time.Second
+const merkleTimeout = 10 * time.Second
+
+// messageSyncName is the name the message cache is synced under.
+const messageSyncName = "gossip/messages"
+
+// Syncable is a key-value dataset that nodes reconcile with Merkle trees.
+type Syncable interface {
+	// Digests returns a digest of the value of every key. Keys whose
+	// digests differ between two nodes are exchanged.
+	Digests() map[string][]byte
+	// Value returns the value of key.
+	Value(key string) ([]byte, bool)
+	// Merge takes the value of key a peer sent.
+	Merge(key string, value []byte) error
+}
+
+type SyncResult struct {
+	// Pulled is the number of values received, and Pushed the number of
+	// values sent.
+	Pulled int
+	Pushed int
+}
+
+// AddSyncable registers s to be synced with neighbors under name. Nodes
+// sync every 30 seconds with a random neighbor, and when SyncWith is
+// called. A nil s removes it.
+func (node *Node) AddSyncable(name string, s Syncable) {
+	node.syncables.lock.Lock()
+	defer node.syncables.lock.Unlock()
+	if s == nil {
+		delete(node.syncables.entries, name)
+		return
+	}
+	node.syncables.entries[name] = s
+}
+
+// SetMessageSync makes the node sync its message cache with neighbors, so
+// that it receives the recent messages it missed.
+func (node *Node) SetMessageSync(enabled bool) {
+	if enabled {
+		node.AddSyncable(messageSyncName, &messageSync{node})
+	} else {
+		node.AddSyncable(messageSyncName, nil)
+	}
+}
+
+// SyncWith reconciles the dataset called name with nodeId. The nodes
+// compare the hashes of their trees level by level, descend only into the
+// subtrees that differ, and then exchange the values of the keys whose
+// digests differ.
+func (node *Node) SyncWith(ctx context.Context, nodeId NodeId, name string) (*SyncResult, error) {
+	s, ok := node.syncables.get(name)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("[gossip] No syncable called %q", name))
+	}
+	conn, err := node.neighbors.GetConn(nodeId)
+	if err != nil {
+		return nil, err
+	}
+	client := NewGossipClient(conn)
+	call := func(req *MerkleReq) (*MerkleRes, error) {
+		req.Topic = node.topic
+		req.Name = name
+		req.NodeId = node.NodeId().String()
+		ctx, cancel := context.WithTimeout(ctx, merkleTimeout)
+		defer cancel()
+		return client.MerkleSync(ctx, req)
+	}
+
+	result := &SyncResult{}
+	local := newMerkleTree(s.Digests())
+	paths := []string{""}
+	for level := 0; level < merkleDepth && len(paths) > 0; level++ {
+		res, err := call(&MerkleReq{Nodes: paths})
+		if err != nil {
+			return result, err
+		}
+		paths = nil
+		for _, n := range res.Nodes {
+			for i, hash := range n.Children {
+				if path := childPath(n.Path, i); !bytes.Equal(hash, local.hashes[path]) {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	res, err := call(&MerkleReq{Buckets: paths})
+	if err != nil {
+		return result, err
+	}
+	remote := make(map[string][]byte, len(res.Digests))
+	var pull []string
+	for _, entry := range res.Digests {
+		remote[entry.Key] = entry.Digest
+		if digest, ok := local.digests[entry.Key]; !ok || !bytes.Equal(digest, entry.Digest) {
+			pull = append(pull, entry.Key)
+		}
+	}
+	var push []*MerkleEntry
+	for _, path := range paths {
+		for _, key := range local.buckets[path] {
+			if digest, ok := remote[key]; ok && bytes.Equal(digest, local.digests[key]) {
+				continue
+			}
+			if value, ok := s.Value(key); ok {
+				push = append(push, &MerkleEntry{Key: key, Value: value})
+			}
+		}
+	}
+
+	for len(pull) > 0 || len(push) > 0 {
+		req := &MerkleReq{}
+		req.Keys, pull = splitKeys(pull)
+		req.Entries, push = splitEntries(push)
+		res, err := call(req)
+		if err != nil {
+			return result, err
+		}
+		result.Pushed += len(req.Entries)
+		for _, entry := range res.Entries {
+			if err := merge(s, nodeId, entry); err != nil {
+				log.Printf("[gossip] Cannot merge %s of %s from node %s: %s", entry.Key, name, nodeId.String(), err.Error())
+				continue
+			}
+			result.Pulled++
+		}
+	}
+	return result, nil
+}
+
+func splitKeys(keys []string) ([]string, []string) {
+	if len(keys) <= merkleBatch {
+		return keys, nil
+	}
+	return keys[:merkleBatch], keys[merkleBatch:]
+}
+
+func splitEntries(entries []*MerkleEntry) ([]*MerkleEntry, []*MerkleEntry) {
+	if len(entries) <= merkleBatch {
+		return entries, nil
+	}
+	return entries[:merkleBatch], entries[merkleBatch:]
+}
+
+// PeerMerger is implemented by syncables that need to know which peer
+// values come from, e.g. to take only the values a peer owns. MergeFrom is
+// called instead of Merge.
+type PeerMerger interface {
+	MergeFrom(from NodeId, key string, value []byte) error
+}
+
+func merge(s Syncable, from NodeId, entry *MerkleEntry) error {
+	if m, ok := s.(PeerMerger); ok {
+		return m.MergeFrom(from, entry.Key, entry.Value)
+	}
+	return s.Merge(entry.Key, entry.Value)
+}
+
+func (node *Node) MerkleSync(ctx context.Context, req *MerkleReq) (*MerkleRes, error) {
+	if req.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	s, ok := node.syncables.get(req.Name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "[From %s] no syncable called %q", node.NodeId().String(), req.Name)
+	}
+	res := &MerkleRes{}
+	if len(req.Nodes) > 0 || len(req.Buckets) > 0 {
+		tree := newMerkleTree(s.Digests())
+		for _, path := range req.Nodes {
+			res.Nodes = append(res.Nodes, &MerkleNode{Path: path, Children: tree.children(path)})
+		}
+		for _, path := range req.Buckets {
+			for _, key := range tree.buckets[path] {
+				res.Digests = append(res.Digests, &MerkleEntry{Key: key, Digest: tree.digests[key]})
+			}
+		}
+	}
+	for _, key := range req.Keys {
+		if value, ok := s.Value(key); ok {
+			res.Entries = append(res.Entries, &MerkleEntry{Key: key, Value: value})
+		}
+	}
+	for _, entry := range req.Entries {
+		if err := merge(s, caller(ctx, NewNodeId(req.NodeId)), entry); err != nil {
+			log.Printf("[gossip] Cannot merge %s of %s from node %s: %s", entry.Key, req.Name, req.NodeId, err.Error())
+		}
+	}
+	return res, nil
+}
+
+// syncPeriodically syncs every dataset with a random neighbor, until the
+// node is closed.
+func (node *Node) syncPeriodically() {
+	for node.sleep(merkleSyncInterval) {
+		for _, name := range node.syncables.names() {
+			nodeIds := node.neighbors.SampleNodeIdWhere(1, func(nodeId NodeId, info *PeerInfo) bool {
+				return info != nil && info.Supports(FeatureMerkleSync)
+			})
+			if len(nodeIds) == 0 {
+				break
+			}
+			if _, err := node.SyncWith(context.Background(), nodeIds[0], name); err != nil && status.Code(err) != codes.NotFound {
+				log.Printf("[gossip] Cannot sync %s with node %s: %s", name, nodeIds[0].String(), err.Error())
+			}
+		}
+	}
+}
+
+// merkleTree hashes the keys of each bucket, and the hashes of the children
+// of each inner node. Empty subtrees have no hash.
+type merkleTree struct {
+	hashes  map[string][]byte
+	buckets map[string][]string
+	digests map[string][]byte
+}
+
+func newMerkleTree(digests map[string][]byte) *merkleTree {
+	tree := &merkleTree{
+		hashes:  make(map[string][]byte),
+		buckets: make(map[string][]string),
+		digests: digests,
+	}
+	for key := range digests {
+		path := bucketPath(key)
+		tree.buckets[path] = append(tree.buckets[path], key)
+	}
+	level := make(map[string]bool)
+	for path, keys := range tree.buckets {
+		sort.Strings(keys)
+		h := sha256.New()
+		for _, key := range keys {
+			writeField(h, []byte(key))
+			writeField(h, digests[key])
+		}
+		tree.hashes[path] = h.Sum(nil)
+		level[path[:merkleDepth-1]] = true
+	}
+	for depth := merkleDepth - 1; depth >= 0; depth-- {
+		parents := make(map[string]bool)
+		for path := range level {
+			h := sha256.New()
+			for i, hash := range tree.children(path) {
+				if hash != nil {
+					h.Write([]byte{byte(i)})
+					h.Write(hash)
+				}
+			}
+			tree.hashes[path] = h.Sum(nil)
+			if depth > 0 {
+				parents[path[:depth-1]] = true
+			}
+		}
+		level = parents
+	}
+	return tree
+}
+
+func (tree *merkleTree) children(path string) [][]byte {
+	children := make([][]byte, merkleFanout)
+	for i := range children {
+		children[i] = tree.hashes[childPath(path, i)]
+	}
+	return children
+}
+
+func bucketPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:merkleDepth]
+}
+
+func childPath(path string, i int) string {
+	return path + string("0123456789abcdef"[i])
+}
+
+// writeField writes data with its length, so that fields cannot run into
+// each other.
+func writeField(h interface{ Write([]byte) (int, error) }, data []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+	h.Write(length[:])
+	h.Write(data)
+}
+
+type syncables struct {
+	entries map[string]Syncable
+	lock    *sync.Mutex
+}
+
+func newSyncables() *syncables {
+	return &syncables{
+		entries: make(map[string]Syncable),
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (s *syncables) get(name string) (Syncable, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	syncable, ok := s.entries[name]
+	return syncable, ok
+}
+
+func (s *syncables) names() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	return names
+}
+
+// messageSync syncs the message cache. Messages are keyed by their hash, so
+// they need no digest, and the ones received go through the node like
+// pulled messages.
+type messageSync struct {
+	node *Node
+}
+
+func (m *messageSync) Digests() map[string][]byte {
+	hashes := m.node.cache.Hashes()
+	digests := make(map[string][]byte, len(hashes))
+	for _, hash := range hashes {
+		digests[hash] = nil
+	}
+	return digests
+}
+
+func (m *messageSync) Value(key string) ([]byte, bool) {
+	data, ok := m.node.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	value, err := proto.Marshal(data)
+	return value, err == nil
+}
+
+func (m *messageSync) Merge(key string, value []byte) error {
+	return errors.New("[gossip] Messages need a sender")
+}
+
+func (m *messageSync) MergeFrom(from NodeId, key string, value []byte) error {
+	data := &GossipData{}
+	if err := proto.Unmarshal(value, data); err != nil {
+		return err
+	}
+	if data.Hash() != key {
+		return errors.New("[gossip] Message does not match its hash")
+	}
+	if reason := m.node.checkSize(data); reason != "" {
+		return errors.New(fmt.Sprintf("[gossip] %s", reason))
+	}
+	data.Sender = from.String()
+	m.node.receive(data)
+	return nil
+}
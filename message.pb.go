@@ -25,6 +25,96 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type SendDataRes_Status int32
+
+const (
+	SendDataRes_NEW       SendDataRes_Status = 0
+	SendDataRes_DUPLICATE SendDataRes_Status = 1
+	SendDataRes_REJECTED  SendDataRes_Status = 2
+	SendDataRes_IGNORED   SendDataRes_Status = 3
+)
+
+var SendDataRes_Status_name = map[int32]string{
+	0: "NEW",
+	1: "DUPLICATE",
+	2: "REJECTED",
+	3: "IGNORED",
+}
+
+var SendDataRes_Status_value = map[string]int32{
+	"NEW":       0,
+	"DUPLICATE": 1,
+	"REJECTED":  2,
+	"IGNORED":   3,
+}
+
+func (x SendDataRes_Status) String() string {
+	return proto.EnumName(SendDataRes_Status_name, int32(x))
+}
+
+func (SendDataRes_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{1, 0}
+}
+
+type BrachaMsg_Phase int32
+
+const (
+	BrachaMsg_SEND  BrachaMsg_Phase = 0
+	BrachaMsg_ECHO  BrachaMsg_Phase = 1
+	BrachaMsg_READY BrachaMsg_Phase = 2
+)
+
+var BrachaMsg_Phase_name = map[int32]string{
+	0: "SEND",
+	1: "ECHO",
+	2: "READY",
+}
+
+var BrachaMsg_Phase_value = map[string]int32{
+	"SEND":  0,
+	"ECHO":  1,
+	"READY": 2,
+}
+
+func (x BrachaMsg_Phase) String() string {
+	return proto.EnumName(BrachaMsg_Phase_name, int32(x))
+}
+
+func (BrachaMsg_Phase) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{11, 0}
+}
+
+type DirectRes_Status int32
+
+const (
+	DirectRes_DELIVERED   DirectRes_Status = 0
+	DirectRes_RELAYED     DirectRes_Status = 1
+	DirectRes_UNREACHABLE DirectRes_Status = 2
+	DirectRes_NO_HANDLER  DirectRes_Status = 3
+)
+
+var DirectRes_Status_name = map[int32]string{
+	0: "DELIVERED",
+	1: "RELAYED",
+	2: "UNREACHABLE",
+	3: "NO_HANDLER",
+}
+
+var DirectRes_Status_value = map[string]int32{
+	"DELIVERED":   0,
+	"RELAYED":     1,
+	"UNREACHABLE": 2,
+	"NO_HANDLER":  3,
+}
+
+func (x DirectRes_Status) String() string {
+	return proto.EnumName(DirectRes_Status_name, int32(x))
+}
+
+func (DirectRes_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{14, 0}
+}
+
 type Empty struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -56,20 +146,68 @@ func (m *Empty) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Empty proto.InternalMessageInfo
 
+type SendDataRes struct {
+	Status               SendDataRes_Status `protobuf:"varint,1,opt,name=status,proto3,enum=gossip.SendDataRes_Status" json:"status,omitempty"`
+	Reason               string             `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *SendDataRes) Reset()         { *m = SendDataRes{} }
+func (m *SendDataRes) String() string { return proto.CompactTextString(m) }
+func (*SendDataRes) ProtoMessage()    {}
+func (*SendDataRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{1}
+}
+
+func (m *SendDataRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendDataRes.Unmarshal(m, b)
+}
+func (m *SendDataRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendDataRes.Marshal(b, m, deterministic)
+}
+func (m *SendDataRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendDataRes.Merge(m, src)
+}
+func (m *SendDataRes) XXX_Size() int {
+	return xxx_messageInfo_SendDataRes.Size(m)
+}
+func (m *SendDataRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendDataRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendDataRes proto.InternalMessageInfo
+
+func (m *SendDataRes) GetStatus() SendDataRes_Status {
+	if m != nil {
+		return m.Status
+	}
+	return SendDataRes_NEW
+}
+
+func (m *SendDataRes) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 type NeighborReq struct {
-	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
-	NodeId               string   `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
-	MaxNum               int32    `protobuf:"varint,3,opt,name=maxNum,proto3" json:"maxNum,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Topic                string        `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	NodeId               string        `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	MaxNum               int32         `protobuf:"varint,3,opt,name=maxNum,proto3" json:"maxNum,omitempty"`
+	Metadata             *NodeMetadata `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *NeighborReq) Reset()         { *m = NeighborReq{} }
 func (m *NeighborReq) String() string { return proto.CompactTextString(m) }
 func (*NeighborReq) ProtoMessage()    {}
 func (*NeighborReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{1}
+	return fileDescriptor_33c57e4bae7b9afd, []int{2}
 }
 
 func (m *NeighborReq) XXX_Unmarshal(b []byte) error {
@@ -111,20 +249,29 @@ func (m *NeighborReq) GetMaxNum() int32 {
 	return 0
 }
 
+func (m *NeighborReq) GetMetadata() *NodeMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 type NeighborRes struct {
-	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
-	NodeId               string   `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
-	Neighbors            []string `protobuf:"bytes,3,rep,name=neighbors,proto3" json:"neighbors,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Topic                string          `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	NodeId               string          `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Neighbors            []string        `protobuf:"bytes,3,rep,name=neighbors,proto3" json:"neighbors,omitempty"`
+	ObservedAddr         string          `protobuf:"bytes,4,opt,name=observedAddr,proto3" json:"observedAddr,omitempty"`
+	Metadata             []*NodeMetadata `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *NeighborRes) Reset()         { *m = NeighborRes{} }
 func (m *NeighborRes) String() string { return proto.CompactTextString(m) }
 func (*NeighborRes) ProtoMessage()    {}
 func (*NeighborRes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{2}
+	return fileDescriptor_33c57e4bae7b9afd, []int{3}
 }
 
 func (m *NeighborRes) XXX_Unmarshal(b []byte) error {
@@ -166,21 +313,102 @@ func (m *NeighborRes) GetNeighbors() []string {
 	return nil
 }
 
+func (m *NeighborRes) GetObservedAddr() string {
+	if m != nil {
+		return m.ObservedAddr
+	}
+	return ""
+}
+
+func (m *NeighborRes) GetMetadata() []*NodeMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type NodeMetadata struct {
+	NodeId               string            `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Version              uint64            `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Entries              map[string]string `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *NodeMetadata) Reset()         { *m = NodeMetadata{} }
+func (m *NodeMetadata) String() string { return proto.CompactTextString(m) }
+func (*NodeMetadata) ProtoMessage()    {}
+func (*NodeMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{4}
+}
+
+func (m *NodeMetadata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeMetadata.Unmarshal(m, b)
+}
+func (m *NodeMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeMetadata.Marshal(b, m, deterministic)
+}
+func (m *NodeMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeMetadata.Merge(m, src)
+}
+func (m *NodeMetadata) XXX_Size() int {
+	return xxx_messageInfo_NodeMetadata.Size(m)
+}
+func (m *NodeMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeMetadata proto.InternalMessageInfo
+
+func (m *NodeMetadata) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *NodeMetadata) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *NodeMetadata) GetEntries() map[string]string {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 type GossipData struct {
-	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
-	NodeId               string   `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
-	Nonce                uint64   `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
-	Payload              []byte   `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Topic                string        `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	NodeId               string        `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Nonce                uint64        `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Payload              []byte        `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	Sender               string        `protobuf:"bytes,5,opt,name=sender,proto3" json:"sender,omitempty"`
+	Fragment             *Fragment     `protobuf:"bytes,6,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Announce             bool          `protobuf:"varint,7,opt,name=announce,proto3" json:"announce,omitempty"`
+	MsgId                string        `protobuf:"bytes,8,opt,name=msgId,proto3" json:"msgId,omitempty"`
+	Size                 uint64        `protobuf:"varint,9,opt,name=size,proto3" json:"size,omitempty"`
+	Codec                string        `protobuf:"bytes,10,opt,name=codec,proto3" json:"codec,omitempty"`
+	Epoch                uint64        `protobuf:"varint,11,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Seq                  uint64        `protobuf:"varint,12,opt,name=seq,proto3" json:"seq,omitempty"`
+	Deps                 []*MessageRef `protobuf:"bytes,13,rep,name=deps,proto3" json:"deps,omitempty"`
+	Ack                  bool          `protobuf:"varint,14,opt,name=ack,proto3" json:"ack,omitempty"`
+	Channel              string        `protobuf:"bytes,15,opt,name=channel,proto3" json:"channel,omitempty"`
+	Selector             string        `protobuf:"bytes,16,opt,name=selector,proto3" json:"selector,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *GossipData) Reset()         { *m = GossipData{} }
 func (m *GossipData) String() string { return proto.CompactTextString(m) }
 func (*GossipData) ProtoMessage()    {}
 func (*GossipData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{3}
+	return fileDescriptor_33c57e4bae7b9afd, []int{5}
 }
 
 func (m *GossipData) XXX_Unmarshal(b []byte) error {
@@ -229,126 +457,1738 @@ func (m *GossipData) GetPayload() []byte {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*Empty)(nil), "gossip.Empty")
-	proto.RegisterType((*NeighborReq)(nil), "gossip.NeighborReq")
-	proto.RegisterType((*NeighborRes)(nil), "gossip.NeighborRes")
-	proto.RegisterType((*GossipData)(nil), "gossip.GossipData")
+func (m *GossipData) GetSender() string {
+	if m != nil {
+		return m.Sender
+	}
+	return ""
 }
 
-func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
+func (m *GossipData) GetFragment() *Fragment {
+	if m != nil {
+		return m.Fragment
+	}
+	return nil
+}
 
-var fileDescriptor_33c57e4bae7b9afd = []byte{
-	// 248 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0xb1, 0x4e, 0xc3, 0x30,
-	0x10, 0x86, 0x1b, 0xd2, 0xa4, 0xcd, 0x41, 0x17, 0x53, 0x21, 0xab, 0x62, 0x88, 0x3c, 0x65, 0x0a,
-	0x12, 0x48, 0x3c, 0x01, 0xa8, 0x62, 0xa9, 0x90, 0x3b, 0x31, 0xba, 0xcd, 0x29, 0x04, 0x11, 0xdb,
-	0xcd, 0x19, 0x89, 0xbe, 0x3d, 0xc2, 0x4e, 0xd4, 0x0e, 0x5d, 0x32, 0x7e, 0x9f, 0xec, 0xdf, 0xbf,
-	0xef, 0x60, 0xd1, 0x22, 0x91, 0xaa, 0xb1, 0xb4, 0x9d, 0x71, 0x86, 0xa5, 0xb5, 0x21, 0x6a, 0xac,
-	0x98, 0x41, 0xf2, 0xda, 0x5a, 0x77, 0x14, 0x5b, 0xb8, 0xde, 0x60, 0x53, 0x7f, 0xee, 0x4c, 0x27,
-	0xf1, 0xc0, 0x96, 0x90, 0x38, 0x63, 0x9b, 0x3d, 0x8f, 0xf2, 0xa8, 0xc8, 0x64, 0x00, 0x76, 0x07,
-	0xa9, 0x36, 0x15, 0xbe, 0x55, 0xfc, 0xca, 0xeb, 0x9e, 0xfe, 0x7d, 0xab, 0x7e, 0x37, 0x3f, 0x2d,
-	0x8f, 0xf3, 0xa8, 0x48, 0x64, 0x4f, 0xe2, 0xe3, 0x3c, 0x94, 0x46, 0x86, 0xde, 0x43, 0xa6, 0xfb,
-	0xcb, 0xc4, 0xe3, 0x3c, 0x2e, 0x32, 0x79, 0x12, 0xe2, 0x0b, 0x60, 0xed, 0xbf, 0xf0, 0xa2, 0x9c,
-	0x1a, 0x99, 0xbc, 0x84, 0x44, 0x1b, 0xbd, 0x47, 0xdf, 0x76, 0x2a, 0x03, 0x30, 0x0e, 0x33, 0xab,
-	0x8e, 0xdf, 0x46, 0x55, 0x7c, 0x9a, 0x47, 0xc5, 0x8d, 0x1c, 0xf0, 0xf1, 0x00, 0x69, 0x78, 0x8b,
-	0x3d, 0xc3, 0x7c, 0x8d, 0xee, 0x1d, 0xb1, 0x23, 0x76, 0x5b, 0x86, 0x19, 0x96, 0x67, 0x73, 0x5b,
-	0x5d, 0x90, 0x24, 0x26, 0xec, 0x01, 0xe6, 0x5b, 0xd4, 0x95, 0xef, 0xca, 0x86, 0x23, 0xa7, 0xfe,
-	0xab, 0xc5, 0xe0, 0xc2, 0x32, 0x26, 0xbb, 0xd4, 0xaf, 0xe9, 0xe9, 0x2f, 0x00, 0x00, 0xff, 0xff,
-	0x66, 0x27, 0xf0, 0x24, 0xb7, 0x01, 0x00, 0x00,
+func (m *GossipData) GetAnnounce() bool {
+	if m != nil {
+		return m.Announce
+	}
+	return false
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *GossipData) GetMsgId() string {
+	if m != nil {
+		return m.MsgId
+	}
+	return ""
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *GossipData) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
 
-// GossipClient is the client API for Gossip service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type GossipClient interface {
-	GetPeers(ctx context.Context, in *NeighborReq, opts ...grpc.CallOption) (*NeighborRes, error)
-	SendData(ctx context.Context, in *GossipData, opts ...grpc.CallOption) (*Empty, error)
+func (m *GossipData) GetCodec() string {
+	if m != nil {
+		return m.Codec
+	}
+	return ""
 }
 
-type gossipClient struct {
-	cc *grpc.ClientConn
+func (m *GossipData) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
 }
 
-func NewGossipClient(cc *grpc.ClientConn) GossipClient {
-	return &gossipClient{cc}
+func (m *GossipData) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
 }
 
-func (c *gossipClient) GetPeers(ctx context.Context, in *NeighborReq, opts ...grpc.CallOption) (*NeighborRes, error) {
-	out := new(NeighborRes)
-	err := c.cc.Invoke(ctx, "/gossip.Gossip/GetPeers", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *GossipData) GetDeps() []*MessageRef {
+	if m != nil {
+		return m.Deps
 	}
-	return out, nil
+	return nil
 }
 
-func (c *gossipClient) SendData(ctx context.Context, in *GossipData, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/gossip.Gossip/SendData", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *GossipData) GetAck() bool {
+	if m != nil {
+		return m.Ack
 	}
-	return out, nil
+	return false
 }
 
-// GossipServer is the server API for Gossip service.
-type GossipServer interface {
-	GetPeers(context.Context, *NeighborReq) (*NeighborRes, error)
-	SendData(context.Context, *GossipData) (*Empty, error)
+func (m *GossipData) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
 }
 
-// UnimplementedGossipServer can be embedded to have forward compatible implementations.
-type UnimplementedGossipServer struct {
+func (m *GossipData) GetSelector() string {
+	if m != nil {
+		return m.Selector
+	}
+	return ""
 }
 
-func (*UnimplementedGossipServer) GetPeers(ctx context.Context, req *NeighborReq) (*NeighborRes, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetPeers not implemented")
+type MessageRef struct {
+	Origin               string   `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"`
+	Epoch                uint64   `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Seq                  uint64   `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
-func (*UnimplementedGossipServer) SendData(ctx context.Context, req *GossipData) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SendData not implemented")
+
+func (m *MessageRef) Reset()         { *m = MessageRef{} }
+func (m *MessageRef) String() string { return proto.CompactTextString(m) }
+func (*MessageRef) ProtoMessage()    {}
+func (*MessageRef) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{6}
 }
 
-func RegisterGossipServer(s *grpc.Server, srv GossipServer) {
-	s.RegisterService(&_Gossip_serviceDesc, srv)
+func (m *MessageRef) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MessageRef.Unmarshal(m, b)
+}
+func (m *MessageRef) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MessageRef.Marshal(b, m, deterministic)
+}
+func (m *MessageRef) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MessageRef.Merge(m, src)
+}
+func (m *MessageRef) XXX_Size() int {
+	return xxx_messageInfo_MessageRef.Size(m)
+}
+func (m *MessageRef) XXX_DiscardUnknown() {
+	xxx_messageInfo_MessageRef.DiscardUnknown(m)
 }
 
-func _Gossip_GetPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(NeighborReq)
-	if err := dec(in); err != nil {
-		return nil, err
+var xxx_messageInfo_MessageRef proto.InternalMessageInfo
+
+func (m *MessageRef) GetOrigin() string {
+	if m != nil {
+		return m.Origin
 	}
-	if interceptor == nil {
-		return srv.(GossipServer).GetPeers(ctx, in)
+	return ""
+}
+
+func (m *MessageRef) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/gossip.Gossip/GetPeers",
+	return 0
+}
+
+func (m *MessageRef) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GossipServer).GetPeers(ctx, req.(*NeighborReq))
+	return 0
+}
+
+type Fragment struct {
+	Digest               []byte   `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
+	Index                uint32   `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Count                uint32   `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Size                 uint64   `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Fragment) Reset()         { *m = Fragment{} }
+func (m *Fragment) String() string { return proto.CompactTextString(m) }
+func (*Fragment) ProtoMessage()    {}
+func (*Fragment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{7}
+}
+
+func (m *Fragment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Fragment.Unmarshal(m, b)
+}
+func (m *Fragment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Fragment.Marshal(b, m, deterministic)
+}
+func (m *Fragment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Fragment.Merge(m, src)
+}
+func (m *Fragment) XXX_Size() int {
+	return xxx_messageInfo_Fragment.Size(m)
+}
+func (m *Fragment) XXX_DiscardUnknown() {
+	xxx_messageInfo_Fragment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Fragment proto.InternalMessageInfo
+
+func (m *Fragment) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _Gossip_SendData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GossipData)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *Fragment) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
 	}
-	if interceptor == nil {
-		return srv.(GossipServer).SendData(ctx, in)
+	return 0
+}
+
+func (m *Fragment) GetCount() uint32 {
+	if m != nil {
+		return m.Count
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
+	return 0
+}
+
+func (m *Fragment) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type FetchReq struct {
+	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	MsgId                string   `protobuf:"bytes,2,opt,name=msgId,proto3" json:"msgId,omitempty"`
+	Origin               string   `protobuf:"bytes,3,opt,name=origin,proto3" json:"origin,omitempty"`
+	Epoch                uint64   `protobuf:"varint,4,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Seq                  uint64   `protobuf:"varint,5,opt,name=seq,proto3" json:"seq,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FetchReq) Reset()         { *m = FetchReq{} }
+func (m *FetchReq) String() string { return proto.CompactTextString(m) }
+func (*FetchReq) ProtoMessage()    {}
+func (*FetchReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{8}
+}
+
+func (m *FetchReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FetchReq.Unmarshal(m, b)
+}
+func (m *FetchReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FetchReq.Marshal(b, m, deterministic)
+}
+func (m *FetchReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchReq.Merge(m, src)
+}
+func (m *FetchReq) XXX_Size() int {
+	return xxx_messageInfo_FetchReq.Size(m)
+}
+func (m *FetchReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchReq proto.InternalMessageInfo
+
+func (m *FetchReq) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *FetchReq) GetMsgId() string {
+	if m != nil {
+		return m.MsgId
+	}
+	return ""
+}
+
+func (m *FetchReq) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *FetchReq) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *FetchReq) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+type HandshakeReq struct {
+	Topic                string            `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	NodeId               string            `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Version              uint32            `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	MinVersion           uint32            `protobuf:"varint,4,opt,name=minVersion,proto3" json:"minVersion,omitempty"`
+	Features             []string          `protobuf:"bytes,5,rep,name=features,proto3" json:"features,omitempty"`
+	Metadata             map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	MetadataVersion      uint64            `protobuf:"varint,7,opt,name=metadataVersion,proto3" json:"metadataVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *HandshakeReq) Reset()         { *m = HandshakeReq{} }
+func (m *HandshakeReq) String() string { return proto.CompactTextString(m) }
+func (*HandshakeReq) ProtoMessage()    {}
+func (*HandshakeReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{9}
+}
+
+func (m *HandshakeReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HandshakeReq.Unmarshal(m, b)
+}
+func (m *HandshakeReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HandshakeReq.Marshal(b, m, deterministic)
+}
+func (m *HandshakeReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HandshakeReq.Merge(m, src)
+}
+func (m *HandshakeReq) XXX_Size() int {
+	return xxx_messageInfo_HandshakeReq.Size(m)
+}
+func (m *HandshakeReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_HandshakeReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HandshakeReq proto.InternalMessageInfo
+
+func (m *HandshakeReq) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *HandshakeReq) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *HandshakeReq) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *HandshakeReq) GetMinVersion() uint32 {
+	if m != nil {
+		return m.MinVersion
+	}
+	return 0
+}
+
+func (m *HandshakeReq) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *HandshakeReq) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *HandshakeReq) GetMetadataVersion() uint64 {
+	if m != nil {
+		return m.MetadataVersion
+	}
+	return 0
+}
+
+type HandshakeRes struct {
+	Topic                string            `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	NodeId               string            `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Version              uint32            `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	MinVersion           uint32            `protobuf:"varint,4,opt,name=minVersion,proto3" json:"minVersion,omitempty"`
+	Features             []string          `protobuf:"bytes,5,rep,name=features,proto3" json:"features,omitempty"`
+	Metadata             map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	MetadataVersion      uint64            `protobuf:"varint,7,opt,name=metadataVersion,proto3" json:"metadataVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *HandshakeRes) Reset()         { *m = HandshakeRes{} }
+func (m *HandshakeRes) String() string { return proto.CompactTextString(m) }
+func (*HandshakeRes) ProtoMessage()    {}
+func (*HandshakeRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{10}
+}
+
+func (m *HandshakeRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HandshakeRes.Unmarshal(m, b)
+}
+func (m *HandshakeRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HandshakeRes.Marshal(b, m, deterministic)
+}
+func (m *HandshakeRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HandshakeRes.Merge(m, src)
+}
+func (m *HandshakeRes) XXX_Size() int {
+	return xxx_messageInfo_HandshakeRes.Size(m)
+}
+func (m *HandshakeRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_HandshakeRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HandshakeRes proto.InternalMessageInfo
+
+func (m *HandshakeRes) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *HandshakeRes) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *HandshakeRes) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *HandshakeRes) GetMinVersion() uint32 {
+	if m != nil {
+		return m.MinVersion
+	}
+	return 0
+}
+
+func (m *HandshakeRes) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *HandshakeRes) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *HandshakeRes) GetMetadataVersion() uint64 {
+	if m != nil {
+		return m.MetadataVersion
+	}
+	return 0
+}
+
+type BrachaMsg struct {
+	Topic                string          `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Phase                BrachaMsg_Phase `protobuf:"varint,2,opt,name=phase,proto3,enum=gossip.BrachaMsg_Phase" json:"phase,omitempty"`
+	Origin               string          `protobuf:"bytes,3,opt,name=origin,proto3" json:"origin,omitempty"`
+	Nonce                uint64          `protobuf:"varint,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Payload              []byte          `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	Sender               string          `protobuf:"bytes,6,opt,name=sender,proto3" json:"sender,omitempty"`
+	Signature            []byte          `protobuf:"bytes,7,opt,name=signature,proto3" json:"signature,omitempty"`
+	Timestamp            int64           `protobuf:"varint,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *BrachaMsg) Reset()         { *m = BrachaMsg{} }
+func (m *BrachaMsg) String() string { return proto.CompactTextString(m) }
+func (*BrachaMsg) ProtoMessage()    {}
+func (*BrachaMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{11}
+}
+
+func (m *BrachaMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BrachaMsg.Unmarshal(m, b)
+}
+func (m *BrachaMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BrachaMsg.Marshal(b, m, deterministic)
+}
+func (m *BrachaMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BrachaMsg.Merge(m, src)
+}
+func (m *BrachaMsg) XXX_Size() int {
+	return xxx_messageInfo_BrachaMsg.Size(m)
+}
+func (m *BrachaMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_BrachaMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BrachaMsg proto.InternalMessageInfo
+
+func (m *BrachaMsg) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *BrachaMsg) GetPhase() BrachaMsg_Phase {
+	if m != nil {
+		return m.Phase
+	}
+	return BrachaMsg_SEND
+}
+
+func (m *BrachaMsg) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *BrachaMsg) GetNonce() uint64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *BrachaMsg) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *BrachaMsg) GetSender() string {
+	if m != nil {
+		return m.Sender
+	}
+	return ""
+}
+
+func (m *BrachaMsg) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *BrachaMsg) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+type AckMsg struct {
+	Topic                string      `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Msg                  *MessageRef `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	NodeId               string      `protobuf:"bytes,3,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *AckMsg) Reset()         { *m = AckMsg{} }
+func (m *AckMsg) String() string { return proto.CompactTextString(m) }
+func (*AckMsg) ProtoMessage()    {}
+func (*AckMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{12}
+}
+
+func (m *AckMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AckMsg.Unmarshal(m, b)
+}
+func (m *AckMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AckMsg.Marshal(b, m, deterministic)
+}
+func (m *AckMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AckMsg.Merge(m, src)
+}
+func (m *AckMsg) XXX_Size() int {
+	return xxx_messageInfo_AckMsg.Size(m)
+}
+func (m *AckMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_AckMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AckMsg proto.InternalMessageInfo
+
+func (m *AckMsg) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *AckMsg) GetMsg() *MessageRef {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (m *AckMsg) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+type DirectMsg struct {
+	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	From                 string   `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To                   string   `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Channel              string   `protobuf:"bytes,4,opt,name=channel,proto3" json:"channel,omitempty"`
+	Payload              []byte   `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	Nonce                uint64   `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Ttl                  uint32   `protobuf:"varint,7,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	RequestId            uint64   `protobuf:"varint,8,opt,name=requestId,proto3" json:"requestId,omitempty"`
+	Response             bool     `protobuf:"varint,9,opt,name=response,proto3" json:"response,omitempty"`
+	Error                string   `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DirectMsg) Reset()         { *m = DirectMsg{} }
+func (m *DirectMsg) String() string { return proto.CompactTextString(m) }
+func (*DirectMsg) ProtoMessage()    {}
+func (*DirectMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{13}
+}
+
+func (m *DirectMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DirectMsg.Unmarshal(m, b)
+}
+func (m *DirectMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DirectMsg.Marshal(b, m, deterministic)
+}
+func (m *DirectMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DirectMsg.Merge(m, src)
+}
+func (m *DirectMsg) XXX_Size() int {
+	return xxx_messageInfo_DirectMsg.Size(m)
+}
+func (m *DirectMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_DirectMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DirectMsg proto.InternalMessageInfo
+
+func (m *DirectMsg) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *DirectMsg) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *DirectMsg) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *DirectMsg) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+func (m *DirectMsg) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *DirectMsg) GetNonce() uint64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *DirectMsg) GetTtl() uint32 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *DirectMsg) GetRequestId() uint64 {
+	if m != nil {
+		return m.RequestId
+	}
+	return 0
+}
+
+func (m *DirectMsg) GetResponse() bool {
+	if m != nil {
+		return m.Response
+	}
+	return false
+}
+
+func (m *DirectMsg) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type DirectRes struct {
+	Status               DirectRes_Status `protobuf:"varint,1,opt,name=status,proto3,enum=gossip.DirectRes_Status" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *DirectRes) Reset()         { *m = DirectRes{} }
+func (m *DirectRes) String() string { return proto.CompactTextString(m) }
+func (*DirectRes) ProtoMessage()    {}
+func (*DirectRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{14}
+}
+
+func (m *DirectRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DirectRes.Unmarshal(m, b)
+}
+func (m *DirectRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DirectRes.Marshal(b, m, deterministic)
+}
+func (m *DirectRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DirectRes.Merge(m, src)
+}
+func (m *DirectRes) XXX_Size() int {
+	return xxx_messageInfo_DirectRes.Size(m)
+}
+func (m *DirectRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_DirectRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DirectRes proto.InternalMessageInfo
+
+func (m *DirectRes) GetStatus() DirectRes_Status {
+	if m != nil {
+		return m.Status
+	}
+	return DirectRes_DELIVERED
+}
+
+type QueryMsg struct {
+	Id                   uint64            `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Payload              []byte            `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Filter               map[string]string `protobuf:"bytes,4,rep,name=filter,proto3" json:"filter,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	RelayAck             bool              `protobuf:"varint,5,opt,name=relayAck,proto3" json:"relayAck,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *QueryMsg) Reset()         { *m = QueryMsg{} }
+func (m *QueryMsg) String() string { return proto.CompactTextString(m) }
+func (*QueryMsg) ProtoMessage()    {}
+func (*QueryMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{15}
+}
+
+func (m *QueryMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryMsg.Unmarshal(m, b)
+}
+func (m *QueryMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryMsg.Marshal(b, m, deterministic)
+}
+func (m *QueryMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryMsg.Merge(m, src)
+}
+func (m *QueryMsg) XXX_Size() int {
+	return xxx_messageInfo_QueryMsg.Size(m)
+}
+func (m *QueryMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryMsg proto.InternalMessageInfo
+
+func (m *QueryMsg) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *QueryMsg) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *QueryMsg) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *QueryMsg) GetFilter() map[string]string {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *QueryMsg) GetRelayAck() bool {
+	if m != nil {
+		return m.RelayAck
+	}
+	return false
+}
+
+type QueryRes struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	NodeId               string   `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Payload              []byte   `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Ack                  bool     `protobuf:"varint,5,opt,name=ack,proto3" json:"ack,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueryRes) Reset()         { *m = QueryRes{} }
+func (m *QueryRes) String() string { return proto.CompactTextString(m) }
+func (*QueryRes) ProtoMessage()    {}
+func (*QueryRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{16}
+}
+
+func (m *QueryRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryRes.Unmarshal(m, b)
+}
+func (m *QueryRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryRes.Marshal(b, m, deterministic)
+}
+func (m *QueryRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryRes.Merge(m, src)
+}
+func (m *QueryRes) XXX_Size() int {
+	return xxx_messageInfo_QueryRes.Size(m)
+}
+func (m *QueryRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryRes proto.InternalMessageInfo
+
+func (m *QueryRes) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *QueryRes) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *QueryRes) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *QueryRes) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *QueryRes) GetAck() bool {
+	if m != nil {
+		return m.Ack
+	}
+	return false
+}
+
+type SizeEstimate struct {
+	Topic                string    `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Epoch                uint64    `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Mins                 []float64 `protobuf:"fixed64,3,rep,packed,name=mins,proto3" json:"mins,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *SizeEstimate) Reset()         { *m = SizeEstimate{} }
+func (m *SizeEstimate) String() string { return proto.CompactTextString(m) }
+func (*SizeEstimate) ProtoMessage()    {}
+func (*SizeEstimate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{17}
+}
+
+func (m *SizeEstimate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SizeEstimate.Unmarshal(m, b)
+}
+func (m *SizeEstimate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SizeEstimate.Marshal(b, m, deterministic)
+}
+func (m *SizeEstimate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SizeEstimate.Merge(m, src)
+}
+func (m *SizeEstimate) XXX_Size() int {
+	return xxx_messageInfo_SizeEstimate.Size(m)
+}
+func (m *SizeEstimate) XXX_DiscardUnknown() {
+	xxx_messageInfo_SizeEstimate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SizeEstimate proto.InternalMessageInfo
+
+func (m *SizeEstimate) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *SizeEstimate) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *SizeEstimate) GetMins() []float64 {
+	if m != nil {
+		return m.Mins
+	}
+	return nil
+}
+
+type AggregateState struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Sum                  float64  `protobuf:"fixed64,2,opt,name=sum,proto3" json:"sum,omitempty"`
+	Weight               float64  `protobuf:"fixed64,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	Min                  float64  `protobuf:"fixed64,4,opt,name=min,proto3" json:"min,omitempty"`
+	Max                  float64  `protobuf:"fixed64,5,opt,name=max,proto3" json:"max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AggregateState) Reset()         { *m = AggregateState{} }
+func (m *AggregateState) String() string { return proto.CompactTextString(m) }
+func (*AggregateState) ProtoMessage()    {}
+func (*AggregateState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{18}
+}
+
+func (m *AggregateState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AggregateState.Unmarshal(m, b)
+}
+func (m *AggregateState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AggregateState.Marshal(b, m, deterministic)
+}
+func (m *AggregateState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AggregateState.Merge(m, src)
+}
+func (m *AggregateState) XXX_Size() int {
+	return xxx_messageInfo_AggregateState.Size(m)
+}
+func (m *AggregateState) XXX_DiscardUnknown() {
+	xxx_messageInfo_AggregateState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AggregateState proto.InternalMessageInfo
+
+func (m *AggregateState) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AggregateState) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *AggregateState) GetWeight() float64 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+func (m *AggregateState) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *AggregateState) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+type AggregateExchange struct {
+	Topic                string            `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Epoch                uint64            `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	States               []*AggregateState `protobuf:"bytes,3,rep,name=states,proto3" json:"states,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *AggregateExchange) Reset()         { *m = AggregateExchange{} }
+func (m *AggregateExchange) String() string { return proto.CompactTextString(m) }
+func (*AggregateExchange) ProtoMessage()    {}
+func (*AggregateExchange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{19}
+}
+
+func (m *AggregateExchange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AggregateExchange.Unmarshal(m, b)
+}
+func (m *AggregateExchange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AggregateExchange.Marshal(b, m, deterministic)
+}
+func (m *AggregateExchange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AggregateExchange.Merge(m, src)
+}
+func (m *AggregateExchange) XXX_Size() int {
+	return xxx_messageInfo_AggregateExchange.Size(m)
+}
+func (m *AggregateExchange) XXX_DiscardUnknown() {
+	xxx_messageInfo_AggregateExchange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AggregateExchange proto.InternalMessageInfo
+
+func (m *AggregateExchange) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *AggregateExchange) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *AggregateExchange) GetStates() []*AggregateState {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+type MerkleEntry struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Digest               []byte   `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MerkleEntry) Reset()         { *m = MerkleEntry{} }
+func (m *MerkleEntry) String() string { return proto.CompactTextString(m) }
+func (*MerkleEntry) ProtoMessage()    {}
+func (*MerkleEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{20}
+}
+
+func (m *MerkleEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleEntry.Unmarshal(m, b)
+}
+func (m *MerkleEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleEntry.Marshal(b, m, deterministic)
+}
+func (m *MerkleEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleEntry.Merge(m, src)
+}
+func (m *MerkleEntry) XXX_Size() int {
+	return xxx_messageInfo_MerkleEntry.Size(m)
+}
+func (m *MerkleEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleEntry proto.InternalMessageInfo
+
+func (m *MerkleEntry) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *MerkleEntry) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *MerkleEntry) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type MerkleNode struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Children             [][]byte `protobuf:"bytes,2,rep,name=children,proto3" json:"children,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MerkleNode) Reset()         { *m = MerkleNode{} }
+func (m *MerkleNode) String() string { return proto.CompactTextString(m) }
+func (*MerkleNode) ProtoMessage()    {}
+func (*MerkleNode) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{21}
+}
+
+func (m *MerkleNode) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleNode.Unmarshal(m, b)
+}
+func (m *MerkleNode) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleNode.Marshal(b, m, deterministic)
+}
+func (m *MerkleNode) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleNode.Merge(m, src)
+}
+func (m *MerkleNode) XXX_Size() int {
+	return xxx_messageInfo_MerkleNode.Size(m)
+}
+func (m *MerkleNode) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleNode.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleNode proto.InternalMessageInfo
+
+func (m *MerkleNode) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *MerkleNode) GetChildren() [][]byte {
+	if m != nil {
+		return m.Children
+	}
+	return nil
+}
+
+type MerkleReq struct {
+	Topic                string         `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Name                 string         `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Nodes                []string       `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Buckets              []string       `protobuf:"bytes,4,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	Keys                 []string       `protobuf:"bytes,5,rep,name=keys,proto3" json:"keys,omitempty"`
+	Entries              []*MerkleEntry `protobuf:"bytes,6,rep,name=entries,proto3" json:"entries,omitempty"`
+	NodeId               string         `protobuf:"bytes,7,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *MerkleReq) Reset()         { *m = MerkleReq{} }
+func (m *MerkleReq) String() string { return proto.CompactTextString(m) }
+func (*MerkleReq) ProtoMessage()    {}
+func (*MerkleReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{22}
+}
+
+func (m *MerkleReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleReq.Unmarshal(m, b)
+}
+func (m *MerkleReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleReq.Marshal(b, m, deterministic)
+}
+func (m *MerkleReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleReq.Merge(m, src)
+}
+func (m *MerkleReq) XXX_Size() int {
+	return xxx_messageInfo_MerkleReq.Size(m)
+}
+func (m *MerkleReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleReq proto.InternalMessageInfo
+
+func (m *MerkleReq) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *MerkleReq) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *MerkleReq) GetNodes() []string {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *MerkleReq) GetBuckets() []string {
+	if m != nil {
+		return m.Buckets
+	}
+	return nil
+}
+
+func (m *MerkleReq) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *MerkleReq) GetEntries() []*MerkleEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *MerkleReq) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+type MerkleRes struct {
+	Nodes                []*MerkleNode  `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Digests              []*MerkleEntry `protobuf:"bytes,2,rep,name=digests,proto3" json:"digests,omitempty"`
+	Entries              []*MerkleEntry `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *MerkleRes) Reset()         { *m = MerkleRes{} }
+func (m *MerkleRes) String() string { return proto.CompactTextString(m) }
+func (*MerkleRes) ProtoMessage()    {}
+func (*MerkleRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{23}
+}
+
+func (m *MerkleRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleRes.Unmarshal(m, b)
+}
+func (m *MerkleRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleRes.Marshal(b, m, deterministic)
+}
+func (m *MerkleRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleRes.Merge(m, src)
+}
+func (m *MerkleRes) XXX_Size() int {
+	return xxx_messageInfo_MerkleRes.Size(m)
+}
+func (m *MerkleRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleRes proto.InternalMessageInfo
+
+func (m *MerkleRes) GetNodes() []*MerkleNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *MerkleRes) GetDigests() []*MerkleEntry {
+	if m != nil {
+		return m.Digests
+	}
+	return nil
+}
+
+func (m *MerkleRes) GetEntries() []*MerkleEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type LogEntry struct {
+	Offset               uint64   `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Timestamp            int64    `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	MsgId                string   `protobuf:"bytes,3,opt,name=msgId,proto3" json:"msgId,omitempty"`
+	Origin               string   `protobuf:"bytes,4,opt,name=origin,proto3" json:"origin,omitempty"`
+	Payload              []byte   `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogEntry) Reset()         { *m = LogEntry{} }
+func (m *LogEntry) String() string { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()    {}
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{24}
+}
+
+func (m *LogEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
+}
+func (m *LogEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogEntry.Marshal(b, m, deterministic)
+}
+func (m *LogEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogEntry.Merge(m, src)
+}
+func (m *LogEntry) XXX_Size() int {
+	return xxx_messageInfo_LogEntry.Size(m)
+}
+func (m *LogEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogEntry proto.InternalMessageInfo
+
+func (m *LogEntry) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *LogEntry) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *LogEntry) GetMsgId() string {
+	if m != nil {
+		return m.MsgId
+	}
+	return ""
+}
+
+func (m *LogEntry) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *LogEntry) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("gossip.SendDataRes_Status", SendDataRes_Status_name, SendDataRes_Status_value)
+	proto.RegisterEnum("gossip.BrachaMsg_Phase", BrachaMsg_Phase_name, BrachaMsg_Phase_value)
+	proto.RegisterEnum("gossip.DirectRes_Status", DirectRes_Status_name, DirectRes_Status_value)
+	proto.RegisterType((*Empty)(nil), "gossip.Empty")
+	proto.RegisterType((*SendDataRes)(nil), "gossip.SendDataRes")
+	proto.RegisterType((*NeighborReq)(nil), "gossip.NeighborReq")
+	proto.RegisterType((*NeighborRes)(nil), "gossip.NeighborRes")
+	proto.RegisterType((*NodeMetadata)(nil), "gossip.NodeMetadata")
+	proto.RegisterMapType((map[string]string)(nil), "gossip.NodeMetadata.EntriesEntry")
+	proto.RegisterType((*GossipData)(nil), "gossip.GossipData")
+	proto.RegisterType((*MessageRef)(nil), "gossip.MessageRef")
+	proto.RegisterType((*Fragment)(nil), "gossip.Fragment")
+	proto.RegisterType((*FetchReq)(nil), "gossip.FetchReq")
+	proto.RegisterType((*HandshakeReq)(nil), "gossip.HandshakeReq")
+	proto.RegisterMapType((map[string]string)(nil), "gossip.HandshakeReq.MetadataEntry")
+	proto.RegisterType((*HandshakeRes)(nil), "gossip.HandshakeRes")
+	proto.RegisterMapType((map[string]string)(nil), "gossip.HandshakeRes.MetadataEntry")
+	proto.RegisterType((*BrachaMsg)(nil), "gossip.BrachaMsg")
+	proto.RegisterType((*AckMsg)(nil), "gossip.AckMsg")
+	proto.RegisterType((*DirectMsg)(nil), "gossip.DirectMsg")
+	proto.RegisterType((*DirectRes)(nil), "gossip.DirectRes")
+	proto.RegisterType((*QueryMsg)(nil), "gossip.QueryMsg")
+	proto.RegisterMapType((map[string]string)(nil), "gossip.QueryMsg.FilterEntry")
+	proto.RegisterType((*QueryRes)(nil), "gossip.QueryRes")
+	proto.RegisterType((*SizeEstimate)(nil), "gossip.SizeEstimate")
+	proto.RegisterType((*AggregateState)(nil), "gossip.AggregateState")
+	proto.RegisterType((*AggregateExchange)(nil), "gossip.AggregateExchange")
+	proto.RegisterType((*MerkleEntry)(nil), "gossip.MerkleEntry")
+	proto.RegisterType((*MerkleNode)(nil), "gossip.MerkleNode")
+	proto.RegisterType((*MerkleReq)(nil), "gossip.MerkleReq")
+	proto.RegisterType((*MerkleRes)(nil), "gossip.MerkleRes")
+	proto.RegisterType((*LogEntry)(nil), "gossip.LogEntry")
+}
+
+func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
+
+var fileDescriptor_33c57e4bae7b9afd = []byte{
+	// 1641 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x58, 0x4b, 0x6f, 0xdb, 0xce,
+	0x11, 0x17, 0x1f, 0x7a, 0x70, 0xf4, 0x88, 0xb2, 0x35, 0x52, 0x56, 0x08, 0x0a, 0x97, 0x28, 0x02,
+	0x1d, 0x12, 0x21, 0x70, 0xd2, 0xa2, 0x79, 0xa0, 0x80, 0x62, 0x31, 0xb1, 0x0b, 0x59, 0x49, 0xd7,
+	0x49, 0x8a, 0x00, 0x05, 0x0a, 0x9a, 0x5c, 0x4b, 0x84, 0x44, 0x52, 0xe1, 0xae, 0x5c, 0x2b, 0xe7,
+	0x16, 0xe8, 0xbd, 0x45, 0x3f, 0x41, 0xef, 0x45, 0x8f, 0xb9, 0xf5, 0x4b, 0xf4, 0x83, 0xf4, 0x1b,
+	0x14, 0xbb, 0xcb, 0x25, 0x29, 0x59, 0x4a, 0xff, 0x3e, 0xfd, 0xf1, 0x3f, 0x69, 0x67, 0x76, 0x76,
+	0x76, 0xe6, 0x37, 0x3b, 0x0f, 0x0a, 0xda, 0x11, 0xa1, 0xd4, 0x9b, 0x92, 0xc1, 0x32, 0x4d, 0x58,
+	0x82, 0x6a, 0xd3, 0x84, 0xd2, 0x70, 0xe9, 0xd4, 0xa1, 0xea, 0x46, 0x4b, 0xb6, 0x76, 0xfe, 0xae,
+	0x41, 0xf3, 0x9c, 0xc4, 0xc1, 0xc8, 0x63, 0x1e, 0x26, 0x14, 0x1d, 0x41, 0x8d, 0x32, 0x8f, 0xad,
+	0xa8, 0xad, 0x1d, 0x6a, 0xfd, 0xce, 0x51, 0x6f, 0x20, 0x4f, 0x0c, 0x4a, 0x42, 0x83, 0x73, 0x21,
+	0x81, 0x33, 0x49, 0x74, 0x0f, 0x6a, 0x29, 0xf1, 0x68, 0x12, 0xdb, 0xfa, 0xa1, 0xd6, 0xb7, 0x70,
+	0x46, 0x39, 0x2f, 0xa0, 0x26, 0x25, 0x51, 0x1d, 0x8c, 0x89, 0xfb, 0xbb, 0x6e, 0x05, 0xb5, 0xc1,
+	0x1a, 0x7d, 0x78, 0x37, 0x3e, 0x3d, 0x1e, 0xbe, 0x77, 0xbb, 0x1a, 0x6a, 0x41, 0x03, 0xbb, 0xbf,
+	0x71, 0x8f, 0xdf, 0xbb, 0xa3, 0xae, 0x8e, 0x9a, 0x50, 0x3f, 0x7d, 0x33, 0x79, 0x8b, 0xdd, 0x51,
+	0xd7, 0x70, 0xfe, 0xac, 0x41, 0x73, 0x42, 0xc2, 0xe9, 0xec, 0x22, 0x49, 0x31, 0xf9, 0x8c, 0x0e,
+	0xa0, 0xca, 0x92, 0x65, 0xe8, 0x0b, 0xbb, 0x2c, 0x2c, 0x09, 0x7e, 0x75, 0x9c, 0x04, 0xe4, 0x34,
+	0x50, 0x57, 0x4b, 0x8a, 0xf3, 0x23, 0xef, 0x7a, 0xb2, 0x8a, 0x6c, 0xe3, 0x50, 0xeb, 0x57, 0x71,
+	0x46, 0xa1, 0xc7, 0xd0, 0x88, 0x08, 0xf3, 0x02, 0x8f, 0x79, 0xb6, 0x79, 0xa8, 0xf5, 0x9b, 0x47,
+	0x07, 0xca, 0xc1, 0x49, 0x12, 0x90, 0xb3, 0x6c, 0x0f, 0xe7, 0x52, 0xce, 0x3f, 0x37, 0xec, 0xa0,
+	0xb7, 0xb4, 0xe3, 0x3e, 0x58, 0x71, 0x76, 0x98, 0xda, 0xc6, 0xa1, 0xd1, 0xb7, 0x70, 0xc1, 0x40,
+	0x0e, 0xb4, 0x92, 0x0b, 0x4a, 0xd2, 0x2b, 0x12, 0x0c, 0x83, 0x20, 0x15, 0x16, 0x59, 0x78, 0x83,
+	0xb7, 0x61, 0x71, 0xf5, 0xd0, 0xf8, 0x0e, 0x16, 0x7f, 0xd5, 0xa0, 0x55, 0xde, 0x2a, 0x19, 0xa7,
+	0x6d, 0x18, 0x67, 0x43, 0xfd, 0x8a, 0xa4, 0x34, 0xcc, 0x02, 0x67, 0x62, 0x45, 0xa2, 0x17, 0x50,
+	0x27, 0x31, 0x4b, 0x43, 0x22, 0x8d, 0x6e, 0x1e, 0xfd, 0x6c, 0xd7, 0x9d, 0x03, 0x57, 0xca, 0xf0,
+	0x9f, 0x35, 0x56, 0x27, 0x7a, 0xcf, 0xa1, 0x55, 0xde, 0x40, 0x5d, 0x30, 0xe6, 0x64, 0x9d, 0xdd,
+	0xcd, 0x97, 0x1c, 0xc3, 0x2b, 0x6f, 0xb1, 0x22, 0x19, 0x58, 0x92, 0x78, 0xae, 0xff, 0x4a, 0x73,
+	0xfe, 0x61, 0x00, 0xbc, 0x11, 0x37, 0xf1, 0xb7, 0x76, 0x4b, 0xb0, 0x0f, 0xa0, 0x1a, 0x27, 0xb1,
+	0x4f, 0x44, 0xcc, 0x4d, 0x2c, 0x09, 0xee, 0xe5, 0xd2, 0x5b, 0x2f, 0x12, 0x2f, 0x10, 0xf8, 0xb6,
+	0xb0, 0x22, 0xb9, 0x1e, 0x4a, 0xe2, 0x80, 0xa4, 0x76, 0x55, 0xea, 0x91, 0x14, 0x7a, 0x08, 0x8d,
+	0xcb, 0xd4, 0x9b, 0x46, 0x24, 0x66, 0x76, 0x4d, 0x3c, 0x92, 0xae, 0x72, 0xff, 0x75, 0xc6, 0xc7,
+	0xb9, 0x04, 0xea, 0x41, 0xc3, 0x8b, 0xe3, 0x64, 0xc5, 0x2f, 0xae, 0x1f, 0x6a, 0xfd, 0x06, 0xce,
+	0x69, 0x6e, 0x51, 0x44, 0xa7, 0xa7, 0x81, 0xdd, 0x90, 0xf6, 0x0b, 0x02, 0x21, 0x30, 0x69, 0xf8,
+	0x85, 0xd8, 0x96, 0x30, 0x53, 0xac, 0xb9, 0xa4, 0x9f, 0x04, 0xc4, 0xb7, 0x41, 0x4a, 0x0a, 0x82,
+	0x73, 0xc9, 0x32, 0xf1, 0x67, 0x76, 0x53, 0x7a, 0x24, 0x08, 0x0e, 0x28, 0x25, 0x9f, 0xed, 0x96,
+	0xe0, 0xf1, 0x25, 0x7a, 0x00, 0x66, 0x40, 0x96, 0xd4, 0x6e, 0x8b, 0x60, 0x21, 0x65, 0xed, 0x99,
+	0xcc, 0x7d, 0x4c, 0x2e, 0xb1, 0xd8, 0xe7, 0x27, 0x3d, 0x7f, 0x6e, 0x77, 0x84, 0x99, 0x7c, 0xc9,
+	0xd1, 0xf1, 0x67, 0x5e, 0x1c, 0x93, 0x85, 0x7d, 0x47, 0xdc, 0xac, 0x48, 0xee, 0x17, 0x25, 0x0b,
+	0xe2, 0xb3, 0x24, 0xb5, 0xbb, 0x62, 0x2b, 0xa7, 0x9d, 0x31, 0x40, 0xa1, 0x9b, 0xe3, 0x98, 0xa4,
+	0xe1, 0x34, 0x8c, 0xd5, 0xfb, 0x92, 0x54, 0x61, 0xbd, 0xbe, 0xc3, 0x7a, 0x23, 0xb7, 0xde, 0xb9,
+	0x80, 0x86, 0xc2, 0x95, 0xeb, 0x0a, 0xc2, 0x29, 0xa1, 0x4c, 0xe8, 0x6a, 0xe1, 0x8c, 0xe2, 0xba,
+	0xc2, 0x38, 0x20, 0xd7, 0x42, 0x57, 0x1b, 0x4b, 0x42, 0xa2, 0xb6, 0x8a, 0x99, 0xd0, 0xd6, 0xc6,
+	0x92, 0xc8, 0xf1, 0x35, 0x0b, 0x7c, 0x9d, 0x2b, 0x68, 0xbc, 0x26, 0xcc, 0x9f, 0xed, 0x2f, 0x25,
+	0x79, 0xac, 0xf4, 0x72, 0xac, 0x0a, 0xdf, 0x8c, 0xdd, 0xbe, 0x99, 0x3b, 0x7c, 0xab, 0x16, 0xbe,
+	0x7d, 0xd5, 0xa1, 0x75, 0xe2, 0xc5, 0x01, 0x9d, 0x79, 0x73, 0x72, 0xfb, 0x3a, 0x56, 0x4a, 0x51,
+	0xe9, 0xa2, 0x22, 0xd1, 0x4f, 0x01, 0xa2, 0x30, 0xfe, 0x98, 0x6d, 0x9a, 0x62, 0xb3, 0xc4, 0xe1,
+	0xe1, 0xbb, 0x24, 0x1e, 0x5b, 0xa5, 0x84, 0x8a, 0xba, 0x61, 0xe1, 0x9c, 0x46, 0xbf, 0x2e, 0xd5,
+	0x94, 0x9a, 0x78, 0x32, 0x8e, 0x7a, 0x32, 0x65, 0x5b, 0x07, 0x2a, 0xd1, 0x65, 0x82, 0xe7, 0x67,
+	0x50, 0x1f, 0xee, 0xa8, 0xb5, 0x32, 0xa0, 0x2e, 0x5c, 0xde, 0x66, 0xf7, 0x5e, 0x40, 0x7b, 0x43,
+	0xc9, 0xad, 0x8a, 0xc1, 0x16, 0x76, 0xf4, 0x07, 0x84, 0x1d, 0xfd, 0xbe, 0xb1, 0xfb, 0x9b, 0x0e,
+	0xd6, 0xab, 0xd4, 0xf3, 0x67, 0xde, 0x19, 0x9d, 0xee, 0x01, 0xee, 0x11, 0x54, 0x97, 0x33, 0x8f,
+	0xca, 0xd3, 0x9d, 0xa3, 0x1f, 0x2b, 0x3f, 0xf2, 0x73, 0x83, 0x77, 0x7c, 0x1b, 0x4b, 0xa9, 0x6f,
+	0xa5, 0x82, 0x2c, 0xbb, 0xe6, 0x9e, 0xb2, 0x5b, 0xdd, 0x57, 0x76, 0x6b, 0x1b, 0x65, 0xf7, 0x3e,
+	0x58, 0x34, 0x9c, 0xc6, 0x02, 0x67, 0x81, 0x49, 0x0b, 0x17, 0x0c, 0xbe, 0xcb, 0xc2, 0x88, 0x50,
+	0xe6, 0x45, 0x4b, 0x51, 0x4e, 0x0d, 0x5c, 0x30, 0x9c, 0x07, 0x50, 0x15, 0xb6, 0xa2, 0x06, 0x98,
+	0xe7, 0xee, 0x64, 0xd4, 0xad, 0xf0, 0x95, 0x7b, 0x7c, 0xf2, 0xb6, 0xab, 0x21, 0x0b, 0xaa, 0xd8,
+	0x1d, 0x8e, 0x3e, 0x75, 0x75, 0xe7, 0xf7, 0x50, 0x1b, 0xfa, 0xf3, 0xfd, 0x90, 0xfc, 0x1c, 0x8c,
+	0x88, 0x4e, 0x05, 0x20, 0xbb, 0xeb, 0x28, 0xdf, 0x2e, 0xbd, 0x38, 0xa3, 0xfc, 0xe2, 0x9c, 0xff,
+	0x6a, 0x60, 0x8d, 0xc2, 0x94, 0xf8, 0x6c, 0xff, 0x0d, 0x08, 0xcc, 0xcb, 0x34, 0x89, 0xb2, 0x88,
+	0x89, 0x35, 0xea, 0x80, 0xce, 0x92, 0x4c, 0x97, 0xce, 0x92, 0x72, 0x51, 0x36, 0x37, 0x8b, 0xf2,
+	0x7e, 0x54, 0xf3, 0x28, 0xd4, 0xca, 0x51, 0xe8, 0x82, 0xc1, 0xd8, 0x42, 0xa0, 0xd9, 0xc6, 0x7c,
+	0xc9, 0x71, 0x4c, 0xc9, 0xe7, 0x15, 0xa1, 0x2c, 0x6b, 0x4b, 0x26, 0x2e, 0x18, 0xfc, 0xe5, 0xa7,
+	0x84, 0x2e, 0x93, 0x98, 0xca, 0xf6, 0xd4, 0xc0, 0x39, 0xcd, 0x6f, 0x20, 0x69, 0x9a, 0xa4, 0xaa,
+	0x45, 0x09, 0xc2, 0xf9, 0x53, 0xee, 0x33, 0xcf, 0xd0, 0xc7, 0x5b, 0xe3, 0xa3, 0xad, 0x20, 0xcc,
+	0x45, 0xb6, 0x86, 0x47, 0xc7, 0xcd, 0x87, 0x44, 0x3e, 0x1b, 0xba, 0xe3, 0xd3, 0x8f, 0x2e, 0x1f,
+	0x00, 0x2b, 0x7c, 0x1a, 0xc4, 0xee, 0x78, 0xf8, 0xc9, 0x1d, 0x75, 0x35, 0x74, 0x07, 0x9a, 0x1f,
+	0x26, 0xd8, 0x1d, 0x1e, 0x9f, 0x0c, 0x5f, 0x8d, 0xdd, 0xae, 0x8e, 0x3a, 0x00, 0x93, 0xb7, 0x7f,
+	0x38, 0x19, 0x4e, 0x46, 0x63, 0x17, 0x77, 0x0d, 0xe7, 0x3f, 0x1a, 0x34, 0x7e, 0xbb, 0x22, 0xe9,
+	0x9a, 0x23, 0xdf, 0x01, 0x3d, 0x94, 0xc3, 0x8e, 0x89, 0xf5, 0x50, 0x34, 0xdc, 0xd8, 0x8b, 0x54,
+	0x96, 0x88, 0x75, 0x19, 0x49, 0x63, 0x13, 0xc9, 0xa7, 0x50, 0xbb, 0x0c, 0x17, 0x8c, 0xf0, 0x79,
+	0x8c, 0xe7, 0xf7, 0x7d, 0xe5, 0x83, 0xd2, 0x3f, 0x78, 0x2d, 0xb6, 0x65, 0x66, 0x67, 0xb2, 0x12,
+	0xb9, 0x85, 0xb7, 0x1e, 0xfa, 0x73, 0xbb, 0xaa, 0x90, 0x93, 0x74, 0xef, 0x19, 0x34, 0x4b, 0x47,
+	0x6e, 0x95, 0xc7, 0x2c, 0x73, 0x8b, 0x83, 0xbb, 0xed, 0xd6, 0x37, 0x0a, 0xdf, 0x1e, 0xd7, 0xf2,
+	0x10, 0x9a, 0xa5, 0x10, 0xaa, 0xa9, 0xa0, 0x9a, 0x4f, 0x05, 0xce, 0x04, 0x5a, 0xe7, 0xe1, 0x17,
+	0xe2, 0x52, 0x16, 0x46, 0x1e, 0x23, 0xfb, 0x3b, 0xe6, 0x8e, 0xfe, 0x8e, 0xc0, 0x8c, 0xc2, 0x58,
+	0x0e, 0x8e, 0x1a, 0x16, 0x6b, 0x27, 0x85, 0xce, 0x70, 0x3a, 0x4d, 0xc9, 0xd4, 0x63, 0x84, 0x47,
+	0x9b, 0xe4, 0x21, 0xd1, 0x4a, 0x21, 0xe1, 0xdd, 0x73, 0x25, 0x33, 0x43, 0xc3, 0x7c, 0xc9, 0x3d,
+	0xfc, 0x23, 0x9f, 0x96, 0x65, 0x83, 0xd7, 0x70, 0x46, 0x71, 0xc9, 0x28, 0x94, 0x95, 0x5b, 0xc3,
+	0x7c, 0x29, 0x38, 0xde, 0xb5, 0x5d, 0xcd, 0x38, 0xde, 0xb5, 0x93, 0xc0, 0xdd, 0xfc, 0x4e, 0xf7,
+	0x9a, 0x27, 0xd0, 0xf4, 0x76, 0x8e, 0x0c, 0xe4, 0x5b, 0xce, 0x67, 0xe0, 0x7b, 0xea, 0x1d, 0x6c,
+	0xba, 0x82, 0x33, 0x29, 0xe7, 0x0c, 0x9a, 0x67, 0x24, 0x9d, 0x2f, 0xc8, 0xbe, 0x28, 0x17, 0xb3,
+	0x8d, 0xbe, 0x3d, 0xdb, 0xc8, 0xe8, 0xcb, 0x68, 0x49, 0xc2, 0x79, 0x09, 0x20, 0xd5, 0xf1, 0x91,
+	0x9b, 0xe3, 0xb5, 0xf4, 0xd8, 0x4c, 0xe1, 0xc5, 0xd7, 0xfc, 0xc9, 0xf9, 0xb3, 0x70, 0x11, 0xa4,
+	0x84, 0x0f, 0xf0, 0x46, 0xbf, 0x85, 0x73, 0xda, 0xf9, 0xb7, 0x06, 0x96, 0x3c, 0xbe, 0x7f, 0xe8,
+	0xd8, 0x95, 0x16, 0xa2, 0x8c, 0x04, 0x44, 0x7d, 0xac, 0x48, 0x82, 0xbf, 0xa8, 0x8b, 0x95, 0x3f,
+	0x27, 0x8c, 0x8a, 0x9c, 0xb0, 0xb0, 0x22, 0xb9, 0x8e, 0x39, 0x59, 0xab, 0x36, 0x29, 0xd6, 0xe8,
+	0x51, 0xf1, 0xf5, 0x20, 0x3b, 0xe4, 0x8f, 0x8a, 0x42, 0x9a, 0xe3, 0x93, 0x7f, 0x2f, 0x94, 0x9e,
+	0x71, 0x7d, 0xa3, 0x9a, 0xfe, 0xb5, 0xe4, 0x02, 0x45, 0x7d, 0x65, 0x98, 0xb6, 0x3d, 0xe3, 0x2a,
+	0x8c, 0x94, 0xb1, 0x8f, 0xa0, 0x2e, 0x81, 0xa5, 0xb6, 0xfe, 0x8d, 0xeb, 0x33, 0x99, 0xb2, 0xb5,
+	0xc6, 0xff, 0xb7, 0xd6, 0xf9, 0x8b, 0x06, 0x8d, 0x71, 0x32, 0x95, 0x31, 0xe6, 0x2d, 0xf1, 0xf2,
+	0x92, 0x12, 0x96, 0x65, 0x65, 0x46, 0x6d, 0x36, 0x2b, 0x7d, 0xab, 0x59, 0x15, 0x93, 0xa6, 0xb1,
+	0x7b, 0xd2, 0x34, 0x37, 0xda, 0xeb, 0xde, 0x92, 0x7f, 0xf4, 0x2f, 0x13, 0x6a, 0xf2, 0x63, 0x09,
+	0xfd, 0x12, 0x1a, 0x6f, 0x08, 0x7b, 0x47, 0x48, 0x4a, 0x51, 0x6e, 0x7f, 0xe9, 0xf3, 0xb9, 0xb7,
+	0x83, 0x49, 0x9d, 0x0a, 0xfa, 0x05, 0x34, 0xd4, 0x87, 0x3d, 0xca, 0x21, 0x2d, 0x3e, 0xc0, 0x8a,
+	0x63, 0xa5, 0xcf, 0x7f, 0xa7, 0x82, 0x9e, 0x81, 0x95, 0x0f, 0x3b, 0xe8, 0x60, 0xd7, 0xec, 0xd8,
+	0xdb, 0xc5, 0xe5, 0x47, 0x9f, 0x80, 0x25, 0x06, 0x71, 0x71, 0x65, 0xf1, 0x5d, 0x95, 0xcd, 0xe6,
+	0xbd, 0x1d, 0x46, 0x38, 0x15, 0xf4, 0x10, 0x6a, 0x72, 0x28, 0x41, 0x77, 0x6f, 0x0c, 0x29, 0xbd,
+	0xb6, 0x62, 0xc9, 0x7f, 0x34, 0x2a, 0xe8, 0x01, 0x18, 0x43, 0x7f, 0x8e, 0x3a, 0x79, 0xbe, 0xfa,
+	0xf3, 0x9d, 0x72, 0x8f, 0xa1, 0x26, 0xdb, 0x52, 0xa1, 0x35, 0xef, 0xde, 0xbd, 0xbb, 0x37, 0x3a,
+	0x97, 0x53, 0x41, 0x2f, 0xa1, 0xa5, 0x6a, 0xe2, 0xb9, 0xf8, 0x6a, 0xcb, 0xe1, 0x29, 0x55, 0xcb,
+	0xde, 0x4e, 0xae, 0x53, 0x41, 0x63, 0x40, 0xaa, 0x10, 0xe5, 0x25, 0x84, 0xa2, 0x9f, 0xdc, 0x28,
+	0x2b, 0x4a, 0xa8, 0xb7, 0x7f, 0xcb, 0xa9, 0xa0, 0xa7, 0xaa, 0x3e, 0x9c, 0xaf, 0x63, 0xbf, 0xf0,
+	0x20, 0x4f, 0xfa, 0xde, 0x0d, 0x16, 0x75, 0x2a, 0x17, 0x35, 0xf1, 0x3f, 0xd0, 0x93, 0xff, 0x0d,
+	0x00, 0x2b, 0x3b, 0x9b, 0x8c, 0x18, 0x12, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// GossipClient is the client API for Gossip service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GossipClient interface {
+	GetPeers(ctx context.Context, in *NeighborReq, opts ...grpc.CallOption) (*NeighborRes, error)
+	SendData(ctx context.Context, in *GossipData, opts ...grpc.CallOption) (*SendDataRes, error)
+	Handshake(ctx context.Context, in *HandshakeReq, opts ...grpc.CallOption) (*HandshakeRes, error)
+	FetchData(ctx context.Context, in *FetchReq, opts ...grpc.CallOption) (*GossipData, error)
+	Bracha(ctx context.Context, in *BrachaMsg, opts ...grpc.CallOption) (*Empty, error)
+	Ack(ctx context.Context, in *AckMsg, opts ...grpc.CallOption) (*Empty, error)
+	Direct(ctx context.Context, in *DirectMsg, opts ...grpc.CallOption) (*DirectRes, error)
+	EstimateSize(ctx context.Context, in *SizeEstimate, opts ...grpc.CallOption) (*SizeEstimate, error)
+	ExchangeAggregates(ctx context.Context, in *AggregateExchange, opts ...grpc.CallOption) (*AggregateExchange, error)
+	MerkleSync(ctx context.Context, in *MerkleReq, opts ...grpc.CallOption) (*MerkleRes, error)
+}
+
+type gossipClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGossipClient(cc *grpc.ClientConn) GossipClient {
+	return &gossipClient{cc}
+}
+
+func (c *gossipClient) GetPeers(ctx context.Context, in *NeighborReq, opts ...grpc.CallOption) (*NeighborRes, error) {
+	out := new(NeighborRes)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/GetPeers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) SendData(ctx context.Context, in *GossipData, opts ...grpc.CallOption) (*SendDataRes, error) {
+	out := new(SendDataRes)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/SendData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Handshake(ctx context.Context, in *HandshakeReq, opts ...grpc.CallOption) (*HandshakeRes, error) {
+	out := new(HandshakeRes)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/Handshake", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) FetchData(ctx context.Context, in *FetchReq, opts ...grpc.CallOption) (*GossipData, error) {
+	out := new(GossipData)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/FetchData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Bracha(ctx context.Context, in *BrachaMsg, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/Bracha", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Ack(ctx context.Context, in *AckMsg, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/Ack", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) Direct(ctx context.Context, in *DirectMsg, opts ...grpc.CallOption) (*DirectRes, error) {
+	out := new(DirectRes)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/Direct", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) EstimateSize(ctx context.Context, in *SizeEstimate, opts ...grpc.CallOption) (*SizeEstimate, error) {
+	out := new(SizeEstimate)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/EstimateSize", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) ExchangeAggregates(ctx context.Context, in *AggregateExchange, opts ...grpc.CallOption) (*AggregateExchange, error) {
+	out := new(AggregateExchange)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/ExchangeAggregates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossipClient) MerkleSync(ctx context.Context, in *MerkleReq, opts ...grpc.CallOption) (*MerkleRes, error) {
+	out := new(MerkleRes)
+	err := c.cc.Invoke(ctx, "/gossip.Gossip/MerkleSync", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GossipServer is the server API for Gossip service.
+type GossipServer interface {
+	GetPeers(context.Context, *NeighborReq) (*NeighborRes, error)
+	SendData(context.Context, *GossipData) (*SendDataRes, error)
+	Handshake(context.Context, *HandshakeReq) (*HandshakeRes, error)
+	FetchData(context.Context, *FetchReq) (*GossipData, error)
+	Bracha(context.Context, *BrachaMsg) (*Empty, error)
+	Ack(context.Context, *AckMsg) (*Empty, error)
+	Direct(context.Context, *DirectMsg) (*DirectRes, error)
+	EstimateSize(context.Context, *SizeEstimate) (*SizeEstimate, error)
+	ExchangeAggregates(context.Context, *AggregateExchange) (*AggregateExchange, error)
+	MerkleSync(context.Context, *MerkleReq) (*MerkleRes, error)
+}
+
+// UnimplementedGossipServer can be embedded to have forward compatible implementations.
+type UnimplementedGossipServer struct {
+}
+
+func (*UnimplementedGossipServer) GetPeers(ctx context.Context, req *NeighborReq) (*NeighborRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeers not implemented")
+}
+func (*UnimplementedGossipServer) SendData(ctx context.Context, req *GossipData) (*SendDataRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendData not implemented")
+}
+func (*UnimplementedGossipServer) Handshake(ctx context.Context, req *HandshakeReq) (*HandshakeRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
+func (*UnimplementedGossipServer) FetchData(ctx context.Context, req *FetchReq) (*GossipData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchData not implemented")
+}
+func (*UnimplementedGossipServer) Bracha(ctx context.Context, req *BrachaMsg) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Bracha not implemented")
+}
+func (*UnimplementedGossipServer) Ack(ctx context.Context, req *AckMsg) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ack not implemented")
+}
+func (*UnimplementedGossipServer) Direct(ctx context.Context, req *DirectMsg) (*DirectRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Direct not implemented")
+}
+func (*UnimplementedGossipServer) EstimateSize(ctx context.Context, req *SizeEstimate) (*SizeEstimate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimateSize not implemented")
+}
+func (*UnimplementedGossipServer) ExchangeAggregates(ctx context.Context, req *AggregateExchange) (*AggregateExchange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExchangeAggregates not implemented")
+}
+func (*UnimplementedGossipServer) MerkleSync(ctx context.Context, req *MerkleReq) (*MerkleRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MerkleSync not implemented")
+}
+
+func RegisterGossipServer(s *grpc.Server, srv GossipServer) {
+	s.RegisterService(&_Gossip_serviceDesc, srv)
+}
+
+func _Gossip_GetPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NeighborReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).GetPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/GetPeers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).GetPeers(ctx, req.(*NeighborReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_SendData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipData)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).SendData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
 		FullMethod: "/gossip.Gossip/SendData",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -357,6 +2197,150 @@ func _Gossip_SendData_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Gossip_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Handshake(ctx, req.(*HandshakeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_FetchData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).FetchData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/FetchData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).FetchData(ctx, req.(*FetchReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Bracha_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BrachaMsg)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Bracha(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/Bracha",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Bracha(ctx, req.(*BrachaMsg))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Ack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckMsg)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Ack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/Ack",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Ack(ctx, req.(*AckMsg))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_Direct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DirectMsg)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).Direct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/Direct",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).Direct(ctx, req.(*DirectMsg))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_EstimateSize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SizeEstimate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).EstimateSize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/EstimateSize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).EstimateSize(ctx, req.(*SizeEstimate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_ExchangeAggregates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateExchange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).ExchangeAggregates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/ExchangeAggregates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).ExchangeAggregates(ctx, req.(*AggregateExchange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gossip_MerkleSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MerkleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServer).MerkleSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gossip.Gossip/MerkleSync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServer).MerkleSync(ctx, req.(*MerkleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Gossip_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "gossip.Gossip",
 	HandlerType: (*GossipServer)(nil),
@@ -369,6 +2353,38 @@ var _Gossip_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SendData",
 			Handler:    _Gossip_SendData_Handler,
 		},
+		{
+			MethodName: "Handshake",
+			Handler:    _Gossip_Handshake_Handler,
+		},
+		{
+			MethodName: "FetchData",
+			Handler:    _Gossip_FetchData_Handler,
+		},
+		{
+			MethodName: "Bracha",
+			Handler:    _Gossip_Bracha_Handler,
+		},
+		{
+			MethodName: "Ack",
+			Handler:    _Gossip_Ack_Handler,
+		},
+		{
+			MethodName: "Direct",
+			Handler:    _Gossip_Direct_Handler,
+		},
+		{
+			MethodName: "EstimateSize",
+			Handler:    _Gossip_EstimateSize_Handler,
+		},
+		{
+			MethodName: "ExchangeAggregates",
+			Handler:    _Gossip_ExchangeAggregates_Handler,
+		},
+		{
+			MethodName: "MerkleSync",
+			Handler:    _Gossip_MerkleSync_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "message.proto",
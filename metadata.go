@@ -0,0 +1,103 @@
+package gossip
+
+import (
+	"log"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const metadataChannel = "gossip/metadata"
+const metadataCap = 4096
+
+// Metadata returns the key/value pairs the node publishes about itself.
+func (node *Node) Metadata() map[string]string {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return copyEntries(node.metadata)
+}
+
+// PeerMetadata returns the latest known metadata of a node. Metadata is only
+// taken from the node itself, in handshakes, peer exchange and gossip it
+// sends directly, since nothing stops a relay from making it up.
+func (node *Node) PeerMetadata(nodeId NodeId) (map[string]string, bool) {
+	return node.neighbors.GetMetadata(nodeId)
+}
+
+func (node *Node) ownMetadata() *NodeMetadata {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return &NodeMetadata{
+		NodeId:  node.nodeId.String(),
+		Version: node.metadataVersion,
+		Entries: copyEntries(node.metadata),
+	}
+}
+
+// nextMetadataVersion returns a version greater than the current one, and
+// than those of earlier runs of the node. The caller holds the lock.
+func (node *Node) nextMetadataVersion() uint64 {
+	version := uint64(time.Now().UnixNano())
+	if version <= node.metadataVersion {
+		version = node.metadataVersion + 1
+	}
+	return version
+}
+
+func (node *Node) announceMetadata() {
+	data, err := proto.Marshal(node.ownMetadata())
+	if err != nil {
+		return
+	}
+	if err := node.gossipChannel(metadataChannel, data); err != nil {
+		log.Printf("[gossip] Cannot announce metadata: %s", err.Error())
+	}
+}
+
+// receiveMetadata learns gossiped metadata from its owner. Relayed metadata
+// of a neighbor that is newer than what is known makes the node ask the
+// neighbor itself.
+func (node *Node) receiveMetadata(data *GossipData) {
+	metadata := &NodeMetadata{}
+	if err := proto.Unmarshal(data.Payload, metadata); err != nil || metadata.NodeId != data.NodeId {
+		log.Printf("[gossip] Malformed metadata from %s", data.NodeId)
+		return
+	}
+	if data.From().String() == data.NodeId {
+		node.learnMetadata(metadata)
+		return
+	}
+	owner := NewNodeId(data.NodeId)
+	if _, ok := node.neighbors.GetPeerInfo(owner); !ok {
+		return
+	}
+	if known := node.neighbors.GetMetadataVersions([]NodeId{owner}); len(known) == 0 || known[0].Version < metadata.Version {
+		go node.handshake(owner)
+	}
+}
+
+// learnMetadata records the metadata of another node.
+func (node *Node) learnMetadata(metadata *NodeMetadata) {
+	if metadata == nil || metadata.NodeId == "" || metadata.NodeId == node.NodeId().String() {
+		return
+	}
+	node.neighbors.UpdateMetadata(metadata)
+}
+
+func copyEntries(entries map[string]string) map[string]string {
+	ret := make(map[string]string, len(entries))
+	for k, v := range entries {
+		ret[k] = v
+	}
+	return ret
+}
+
+// matchEntries reports whether entries has all key/value pairs of filter.
+func matchEntries(entries map[string]string, filter map[string]string) bool {
+	for k, v := range filter {
+		if value, ok := entries[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
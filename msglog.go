@@ -0,0 +1,582 @@
+package gossip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const defaultSegmentBytes = 64 << 20
+const logSegmentSuffix = ".log"
+const logSubscribersDir = "subscribers"
+
+// every record is its length and CRC-32 followed by a LogEntry
+const logRecordHeader = 8
+
+// retention runs on every append and at this interval, so that MaxAge
+// applies to idle logs too
+const logRetainInterval = time.Minute
+
+// messages are only checked against the latest entries, so that a log
+// without retention limits does not keep an ever growing index
+const logDedupeWindow = 1 << 18
+
+type MessageLogOptions struct {
+	// SegmentBytes is the size at which the log starts a new segment. It
+	// defaults to 64 MiB.
+	SegmentBytes int64
+	// MaxAge drops segments whose last entry is older, checked on every
+	// append and once a minute. Zero keeps them.
+	MaxAge time.Duration
+	// MaxBytes drops the oldest segments while the log is larger. Zero
+	// means no limit.
+	MaxBytes int64
+	// SyncWrites flushes every entry to disk before it is delivered, so that
+	// it survives a crash of the machine and not only of the process.
+	SyncWrites bool
+}
+
+type logSegment struct {
+	path string
+	// base is the offset of the first entry and last that of the last one,
+	// or base-1 if the segment is empty
+	base     uint64
+	last     uint64
+	size     int64
+	lastTime int64
+}
+
+// MessageLog is an append-only log of delivered messages on disk, split
+// into segments. Entries get consecutive offsets starting at 1, and every
+// message is logged once, even if it is delivered again after a restart,
+// unless it is further back than the retained segments or the last 2^18
+// entries. Subscribers read the log from where they left off.
+type MessageLog struct {
+	dir      string
+	opts     MessageLogOptions
+	segments []*logSegment
+	active   *os.File
+	next     uint64
+	msgIds   map[string]uint64
+	// indexed are the entries in msgIds, oldest first
+	indexed      []logIndexEntry
+	dedupeWindow int
+	appended     chan struct{}
+	closed       bool
+	stop         chan struct{}
+	lock         *sync.Mutex
+}
+
+type logIndexEntry struct {
+	msgId  string
+	offset uint64
+}
+
+// OpenMessageLog opens the log in dir, creating it if needed. A record that
+// was cut off by a crash at the end of the log is dropped.
+func OpenMessageLog(dir string, opts MessageLogOptions) (*MessageLog, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaultSegmentBytes
+	}
+	if err := os.MkdirAll(filepath.Join(dir, logSubscribersDir), 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("[gossip] Cannot create message log %s: %s", dir, err.Error()))
+	}
+	ml := &MessageLog{
+		dir:          dir,
+		opts:         opts,
+		next:         1,
+		msgIds:       make(map[string]uint64),
+		dedupeWindow: logDedupeWindow,
+		appended:     make(chan struct{}),
+		stop:         make(chan struct{}),
+		lock:         &sync.Mutex{},
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("[gossip] Cannot read message log %s: %s", dir, err.Error()))
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, logSegmentSuffix) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, logSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		ml.segments = append(ml.segments, &logSegment{path: filepath.Join(dir, name), base: base})
+	}
+	sort.Slice(ml.segments, func(i, j int) bool {
+		return ml.segments[i].base < ml.segments[j].base
+	})
+	for i, segment := range ml.segments {
+		if err := ml.recover(segment, i == len(ml.segments)-1); err != nil {
+			return nil, err
+		}
+		if segment.last >= ml.next {
+			ml.next = segment.last + 1
+		}
+	}
+	if len(ml.segments) == 0 {
+		if err := ml.rotate(); err != nil {
+			return nil, err
+		}
+	} else {
+		last := ml.segments[len(ml.segments)-1]
+		if ml.active, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return nil, errors.New(fmt.Sprintf("[gossip] Cannot open message log segment %s: %s", last.path, err.Error()))
+		}
+	}
+	ml.retain()
+	go ml.retainPeriodically()
+	return ml, nil
+}
+
+// recover scans a segment at opening. Only the last segment may end with
+// a partial record, which is truncated.
+func (ml *MessageLog) recover(segment *logSegment, isLast bool) error {
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return errors.New(fmt.Sprintf("[gossip] Cannot open message log segment %s: %s", segment.path, err.Error()))
+	}
+	defer file.Close()
+	segment.last = segment.base - 1
+	for {
+		entry, size, err := readLogRecord(file, segment.size)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if !isLast {
+				return errors.New(fmt.Sprintf("[gossip] Corrupt message log segment %s: %s", segment.path, err.Error()))
+			}
+			return os.Truncate(segment.path, segment.size)
+		}
+		segment.size += size
+		segment.last = entry.Offset
+		segment.lastTime = entry.Timestamp
+		ml.index(entry.MsgId, entry.Offset)
+	}
+}
+
+// readLogRecord reads the record at pos. It returns io.EOF at the end of the
+// file.
+func readLogRecord(file *os.File, pos int64) (*LogEntry, int64, error) {
+	var header [logRecordHeader]byte
+	if _, err := file.ReadAt(header[:], pos); err != nil {
+		if err == io.EOF && pos == fileSize(file) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, errors.New("partial record header")
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if int64(length) > fileSize(file)-pos-logRecordHeader {
+		return nil, 0, errors.New("partial record")
+	}
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, pos+logRecordHeader); err != nil {
+		return nil, 0, errors.New("partial record")
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	entry := &LogEntry{}
+	if err := proto.Unmarshal(data, entry); err != nil {
+		return nil, 0, err
+	}
+	return entry, logRecordHeader + int64(length), nil
+}
+
+func fileSize(file *os.File) int64 {
+	info, err := file.Stat()
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// rotate starts a new segment. The caller holds the lock.
+func (ml *MessageLog) rotate() error {
+	path := filepath.Join(ml.dir, fmt.Sprintf("%020d%s", ml.next, logSegmentSuffix))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.New(fmt.Sprintf("[gossip] Cannot create message log segment %s: %s", path, err.Error()))
+	}
+	if ml.active != nil {
+		ml.active.Close()
+	}
+	ml.active = file
+	ml.segments = append(ml.segments, &logSegment{path: path, base: ml.next, last: ml.next - 1})
+	return nil
+}
+
+// Append logs a delivered message and returns its offset. Messages that
+// are logged already are not logged again, and get offset 0.
+func (ml *MessageLog) Append(data *GossipData) (uint64, error) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	if ml.closed {
+		return 0, errors.New("[gossip] Message log is closed")
+	}
+	msgId := data.Hash()
+	if _, ok := ml.msgIds[msgId]; ok {
+		return 0, nil
+	}
+	entry := &LogEntry{
+		Offset:    ml.next,
+		Timestamp: time.Now().UnixNano(),
+		MsgId:     msgId,
+		Origin:    data.NodeId,
+		Payload:   data.Payload,
+	}
+	content, err := proto.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	segment := ml.segments[len(ml.segments)-1]
+	if segment.size > 0 && segment.size+logRecordHeader+int64(len(content)) > ml.opts.SegmentBytes {
+		if err := ml.rotate(); err != nil {
+			return 0, err
+		}
+		segment = ml.segments[len(ml.segments)-1]
+	}
+	record := make([]byte, logRecordHeader+len(content))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(content)))
+	binary.BigEndian.PutUint32(record[4:logRecordHeader], crc32.ChecksumIEEE(content))
+	copy(record[logRecordHeader:], content)
+	if _, err := ml.active.Write(record); err != nil {
+		// drop what was written, so that the next record starts cleanly
+		os.Truncate(segment.path, segment.size)
+		return 0, errors.New(fmt.Sprintf("[gossip] Cannot write message log: %s", err.Error()))
+	}
+	if ml.opts.SyncWrites {
+		if err := ml.active.Sync(); err != nil {
+			return 0, errors.New(fmt.Sprintf("[gossip] Cannot sync message log: %s", err.Error()))
+		}
+	}
+	segment.size += int64(len(record))
+	segment.last = entry.Offset
+	segment.lastTime = entry.Timestamp
+	ml.index(msgId, entry.Offset)
+	ml.next++
+	close(ml.appended)
+	ml.appended = make(chan struct{})
+	ml.retain()
+	return entry.Offset, nil
+}
+
+// retain drops the oldest segments that are past the retention limits. The
+// active segment is always kept. The caller holds the lock.
+func (ml *MessageLog) retain() {
+	var total int64
+	for _, segment := range ml.segments {
+		total += segment.size
+	}
+	cutoff := time.Now().Add(-ml.opts.MaxAge).UnixNano()
+	for len(ml.segments) > 1 {
+		oldest := ml.segments[0]
+		tooOld := ml.opts.MaxAge > 0 && oldest.lastTime < cutoff
+		tooLarge := ml.opts.MaxBytes > 0 && total > ml.opts.MaxBytes
+		if !tooOld && !tooLarge {
+			return
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		total -= oldest.size
+		ml.segments = ml.segments[1:]
+		for len(ml.indexed) > 0 && ml.indexed[0].offset <= oldest.last {
+			ml.forget()
+		}
+	}
+}
+
+func (ml *MessageLog) retainPeriodically() {
+	ticker := time.NewTicker(logRetainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ml.stop:
+			return
+		case <-ticker.C:
+		}
+		ml.lock.Lock()
+		ml.retain()
+		ml.lock.Unlock()
+	}
+}
+
+// index remembers that msgId is logged at offset, and forgets the oldest
+// entries beyond the window. The caller holds the lock.
+func (ml *MessageLog) index(msgId string, offset uint64) {
+	ml.msgIds[msgId] = offset
+	ml.indexed = append(ml.indexed, logIndexEntry{msgId, offset})
+	for len(ml.indexed) > ml.dedupeWindow {
+		ml.forget()
+	}
+}
+
+// forget drops the oldest entry from the index. The caller holds the lock.
+func (ml *MessageLog) forget() {
+	oldest := ml.indexed[0]
+	ml.indexed = ml.indexed[1:]
+	// a message logged again later is indexed under its later offset
+	if ml.msgIds[oldest.msgId] == oldest.offset {
+		delete(ml.msgIds, oldest.msgId)
+	}
+}
+
+// segmentFor returns the segment that holds offset, or the first one after
+// it if it was dropped, and the size of the segment that readers may read.
+func (ml *MessageLog) segmentFor(offset uint64) (*logSegment, int64) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	for _, segment := range ml.segments {
+		if segment.last >= offset || segment == ml.segments[len(ml.segments)-1] {
+			return segment, segment.size
+		}
+	}
+	return nil, 0
+}
+
+func (ml *MessageLog) size(segment *logSegment) int64 {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	return segment.size
+}
+
+// wait returns a channel that is closed when an entry is appended.
+func (ml *MessageLog) wait() <-chan struct{} {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	return ml.appended
+}
+
+// OffsetAt returns the offset of the first entry logged at t or later.
+func (ml *MessageLog) OffsetAt(t time.Time) (uint64, error) {
+	ml.lock.Lock()
+	var segment *logSegment
+	var size int64
+	for _, s := range ml.segments {
+		if s.lastTime >= t.UnixNano() {
+			segment, size = s, s.size
+			break
+		}
+	}
+	next := ml.next
+	ml.lock.Unlock()
+	if segment == nil {
+		return next, nil
+	}
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	for pos := int64(0); pos < size; {
+		entry, n, err := readLogRecord(file, pos)
+		if err != nil {
+			return 0, err
+		}
+		if entry.Timestamp >= t.UnixNano() {
+			return entry.Offset, nil
+		}
+		pos += n
+	}
+	return next, nil
+}
+
+// Close closes the log. Subscriptions return an error from then on.
+func (ml *MessageLog) Close() error {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	if ml.closed {
+		return nil
+	}
+	ml.closed = true
+	close(ml.appended)
+	close(ml.stop)
+	return ml.active.Close()
+}
+
+// Subscription reads the log for a named subscriber. Each entry is
+// returned once; a subscriber that commits the offset of every entry once
+// it handled it resumes after the last one it committed when it
+// subscribes again, so it handles every entry exactly once across
+// restarts. Entries that were dropped by retention before it got to them
+// are skipped.
+type Subscription struct {
+	msgLog  *MessageLog
+	name    string
+	next    uint64
+	segment *logSegment
+	file    *os.File
+	pos     int64
+}
+
+// Subscribe resumes the subscription called name after the last offset it
+// committed, or starts it at the beginning of the log.
+func (ml *MessageLog) Subscribe(name string) (*Subscription, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, errors.New(fmt.Sprintf("[gossip] Invalid subscriber name %q", name))
+	}
+	sub := &Subscription{msgLog: ml, name: name, next: 1}
+	content, err := ioutil.ReadFile(sub.path())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		committed, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("[gossip] Cannot parse offset of subscriber %s: %s", name, err.Error()))
+		}
+		sub.next = committed + 1
+	}
+	return sub, nil
+}
+
+func (sub *Subscription) path() string {
+	return filepath.Join(sub.msgLog.dir, logSubscribersDir, sub.name)
+}
+
+// Seek makes the next entry the one at offset.
+func (sub *Subscription) Seek(offset uint64) {
+	sub.reset()
+	sub.next = offset
+}
+
+// SeekTime makes the next entry the first one logged at t or later.
+func (sub *Subscription) SeekTime(t time.Time) error {
+	offset, err := sub.msgLog.OffsetAt(t)
+	if err != nil {
+		return err
+	}
+	sub.Seek(offset)
+	return nil
+}
+
+// Commit records that the subscriber handled the entries up to offset.
+func (sub *Subscription) Commit(offset uint64) error {
+	return writeFileAtomic(sub.path(), []byte(strconv.FormatUint(offset, 10)))
+}
+
+// Next returns the next entry, waiting for it to be logged until ctx is
+// done.
+func (sub *Subscription) Next(ctx context.Context) (*LogEntry, error) {
+	for {
+		appended := sub.msgLog.wait()
+		entry, err := sub.read()
+		if err != nil || entry != nil {
+			return entry, err
+		}
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// read returns the next entry, or nil at the end of the log.
+func (sub *Subscription) read() (*LogEntry, error) {
+	for {
+		sub.msgLog.lock.Lock()
+		closed := sub.msgLog.closed
+		sub.msgLog.lock.Unlock()
+		if closed {
+			sub.reset()
+			return nil, errors.New("[gossip] Message log is closed")
+		}
+		if sub.file == nil {
+			segment, _ := sub.msgLog.segmentFor(sub.next)
+			file, err := os.Open(segment.path)
+			if err != nil {
+				return nil, err
+			}
+			sub.segment, sub.file, sub.pos = segment, file, 0
+		}
+		if sub.pos >= sub.msgLog.size(sub.segment) {
+			// move on once the segment is complete
+			segment, _ := sub.msgLog.segmentFor(sub.segment.last + 1)
+			if segment == sub.segment {
+				return nil, nil
+			}
+			sub.reset()
+			continue
+		}
+		entry, n, err := readLogRecord(sub.file, sub.pos)
+		if err != nil {
+			return nil, err
+		}
+		sub.pos += n
+		if entry.Offset >= sub.next {
+			sub.next = entry.Offset + 1
+			return entry, nil
+		}
+	}
+}
+
+func (sub *Subscription) reset() {
+	if sub.file != nil {
+		sub.file.Close()
+	}
+	sub.segment, sub.file, sub.pos = nil, nil, 0
+}
+
+// Close releases the subscription. Its committed offset is kept.
+func (sub *Subscription) Close() {
+	sub.reset()
+}
+
+// SetMessageLog makes the node log the messages it delivers in dir, so
+// that subscribers can read them after a restart. It must be called before
+// Join.
+func (node *Node) SetMessageLog(dir string, opts MessageLogOptions) error {
+	msgLog, err := OpenMessageLog(dir, opts)
+	if err != nil {
+		return err
+	}
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.msgLog = msgLog
+	return nil
+}
+
+// Subscribe subscribes to the message log of the node, see
+// MessageLog.Subscribe.
+func (node *Node) Subscribe(name string) (*Subscription, error) {
+	node.lock.Lock()
+	msgLog := node.msgLog
+	node.lock.Unlock()
+	if msgLog == nil {
+		return nil, errors.New("[gossip] The node has no message log")
+	}
+	return msgLog.Subscribe(name)
+}
+
+// logMessage logs a message that is delivered, if the node has a log.
+func (node *Node) logMessage(data *GossipData) {
+	node.lock.Lock()
+	msgLog := node.msgLog
+	node.lock.Unlock()
+	if msgLog == nil {
+		return
+	}
+	if _, err := msgLog.Append(data); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,172 @@
+package gossip
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMessageLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msglog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := MessageLogOptions{SegmentBytes: 256}
+	ml, err := OpenMessageLog(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make([]*GossipData, 20)
+	for i := range messages {
+		messages[i] = &GossipData{NodeId: "a", Nonce: uint64(i), Payload: []byte("message " + strconv.Itoa(i))}
+		if offset, err := ml.Append(messages[i]); err != nil || offset != uint64(i+1) {
+			t.Fatalf("unexpected offset %d, %v", offset, err)
+		}
+	}
+	if offset, _ := ml.Append(messages[3]); offset != 0 {
+		t.Errorf("logged a message twice at %d", offset)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(segments) < 2 {
+		t.Errorf("segments not rotated: %v", segments)
+	}
+
+	sub, err := ml.Subscribe("consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 1; i <= 5; i++ {
+		entry, err := sub.Next(ctx)
+		if err != nil || entry.Offset != uint64(i) || string(entry.Payload) != "message "+strconv.Itoa(i-1) {
+			t.Fatalf("unexpected entry %v, %v", entry, err)
+		}
+		sub.Commit(entry.Offset)
+	}
+	sub.Close()
+	ml.Close()
+
+	// the subscriber resumes after a restart, and a record cut off by a
+	// crash is dropped
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	last, _ := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	last.Write([]byte{0, 0, 1})
+	last.Close()
+	if ml, err = OpenMessageLog(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	defer ml.Close()
+	if offset, _ := ml.Append(messages[7]); offset != 0 {
+		t.Errorf("logged a message twice after a restart at %d", offset)
+	}
+	if sub, err = ml.Subscribe("consumer"); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := sub.Next(ctx); err != nil || entry.Offset != 6 {
+		t.Fatalf("unexpected entry %v, %v", entry, err)
+	}
+
+	// subscribers wait for new entries
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ml.Append(&GossipData{NodeId: "a", Nonce: 100, Payload: []byte("late")})
+	}()
+	sub.Seek(21)
+	if entry, err := sub.Next(ctx); err != nil || string(entry.Payload) != "late" {
+		t.Fatalf("unexpected entry %v, %v", entry, err)
+	}
+
+	// and can start at a point in time
+	if err := sub.SeekTime(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := sub.Next(ctx); err != nil || entry.Offset != 1 {
+		t.Fatalf("unexpected entry %v, %v", entry, err)
+	}
+
+	// retention drops the oldest segments
+	ml.opts.MaxBytes = 512
+	ml.Append(&GossipData{NodeId: "a", Nonce: 101, Payload: []byte("retained")})
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(segments) > 3 {
+		t.Errorf("segments not dropped: %v", segments)
+	}
+	sub.Seek(1)
+	if entry, err := sub.Next(ctx); err != nil || entry.Offset == 1 {
+		t.Errorf("unexpected entry %v, %v", entry, err)
+	}
+	if offset, _ := ml.Append(messages[0]); offset == 0 {
+		t.Error("dropped message still counts as logged")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msglog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.SetMessageLog(dir, MessageLogOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Gossip([]byte("hello"))
+	<-node.GetMsgChan()
+
+	sub, err := node.Subscribe("consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if entry, err := sub.Next(ctx); err != nil || string(entry.Payload) != "hello" || entry.Origin != node.NodeId().String() {
+		t.Fatalf("unexpected entry %v, %v", entry, err)
+	}
+}
+
+func TestMessageLogRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msglog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := MessageLogOptions{SegmentBytes: 256}
+	ml, err := OpenMessageLog(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ml.dedupeWindow = 5
+	messages := make([]*GossipData, 20)
+	for i := range messages {
+		messages[i] = &GossipData{NodeId: "a", Nonce: uint64(i), Payload: []byte("message " + strconv.Itoa(i))}
+		ml.Append(messages[i])
+	}
+	// the index only covers the latest entries
+	if len(ml.msgIds) != 5 || len(ml.indexed) != 5 {
+		t.Errorf("%d message ids indexed", len(ml.msgIds))
+	}
+	if offset, _ := ml.Append(messages[19]); offset != 0 {
+		t.Errorf("logged a recent message twice at %d", offset)
+	}
+	ml.Close()
+
+	// MaxAge applies without appends
+	opts.MaxAge = 50 * time.Millisecond
+	time.Sleep(opts.MaxAge)
+	if ml, err = OpenMessageLog(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	defer ml.Close()
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(segments) != 1 {
+		t.Errorf("old segments kept: %v", segments)
+	}
+	if len(ml.msgIds) != len(ml.indexed) {
+		t.Errorf("%d message ids but %d indexed", len(ml.msgIds), len(ml.indexed))
+	}
+}
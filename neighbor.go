@@ -8,56 +8,123 @@ import (
 	"math/rand"
 	"sort"
 	"sync"
+	"time"
 
-	"github.com/golang-collections/collections/set"
 	"google.golang.org/grpc"
 )
 
 type NeighborList struct {
-	cap       int
-	neighbors *list.List
-	connPool  map[NodeId]*grpc.ClientConn
-	blackList *set.Set
-	lock      *sync.RWMutex
+	cap        int
+	maxRecv    int
+	neighbors  *list.List
+	connPool   map[NodeId]*grpc.ClientConn
+	blackList  map[NodeId]time.Time
+	failures   map[NodeId]int
+	mismatches map[NodeId]int
+	peerInfo   map[NodeId]*PeerInfo
+	metadata   map[NodeId]*NodeMetadata
+	lock       *sync.RWMutex
 }
 
 func NewNeighborList(cap int) *NeighborList {
 	return &NeighborList{
-		cap:       cap,
-		neighbors: list.New(),
-		connPool:  make(map[NodeId]*grpc.ClientConn),
-		blackList: set.New(),
-		lock:      &sync.RWMutex{},
+		cap:        cap,
+		neighbors:  list.New(),
+		connPool:   make(map[NodeId]*grpc.ClientConn),
+		blackList:  make(map[NodeId]time.Time),
+		failures:   make(map[NodeId]int),
+		mismatches: make(map[NodeId]int),
+		peerInfo:   make(map[NodeId]*PeerInfo),
+		metadata:   make(map[NodeId]*NodeMetadata),
+		lock:       &sync.RWMutex{},
 	}
 }
 
+// SetMaxRecvMsgSize sets the largest response neighbors may send, such as
+// a pulled message. It applies to connections dialed afterwards.
+func (nl *NeighborList) SetMaxRecvMsgSize(size int) {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	nl.maxRecv = size
+}
+
+// dial connects to nodeId. The caller holds the lock.
+func (nl *NeighborList) dial(nodeId NodeId) (*grpc.ClientConn, error) {
+	if nl.maxRecv <= 0 {
+		return nodeId.Dial()
+	}
+	return nodeId.Dial(grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(nl.maxRecv)))
+}
+
 func (nl *NeighborList) AddBlackList(nodeId NodeId) {
 	nl.lock.Lock()
 	defer nl.lock.Unlock()
-	nl.blackList.Insert(nodeId)
+	nl.blackList[nodeId] = time.Time{}
 }
 
-func (nl *NeighborList) Update(nodeId NodeId) {
+// BanFor blacklists nodeId until the duration has passed, and drops it from
+// the list.
+func (nl *NeighborList) BanFor(nodeId NodeId, duration time.Duration) {
 	nl.lock.Lock()
 	defer nl.lock.Unlock()
-	if nl.blackList.Has(nodeId) {
+	if expiry, ok := nl.blackList[nodeId]; ok && expiry.IsZero() {
 		return
 	}
+	nl.blackList[nodeId] = time.Now().Add(duration)
+	for e := nl.neighbors.Front(); e != nil; e = e.Next() {
+		if e.Value.(NodeId) == nodeId {
+			nl.remove(e)
+			break
+		}
+	}
+}
+
+func (nl *NeighborList) RemoveBlackList(nodeId NodeId) {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	delete(nl.blackList, nodeId)
+}
+
+func (nl *NeighborList) IsBlackListed(nodeId NodeId) bool {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	return nl.isBlackListed(nodeId)
+}
+
+// isBlackListed drops an expired ban on the way, so it needs the write lock.
+func (nl *NeighborList) isBlackListed(nodeId NodeId) bool {
+	expiry, ok := nl.blackList[nodeId]
+	if !ok {
+		return false
+	}
+	if !expiry.IsZero() && time.Now().After(expiry) {
+		delete(nl.blackList, nodeId)
+		return false
+	}
+	return true
+}
+
+// Update moves nodeId to the front of the list, and reports whether it had
+// to be added.
+func (nl *NeighborList) Update(nodeId NodeId) bool {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	if nl.isBlackListed(nodeId) {
+		return false
+	}
 
 	if _, ok := nl.connPool[nodeId]; !ok {
-		conn, err := nodeId.Dial()
+		conn, err := nl.dial(nodeId)
 		if err != nil {
 			log.Printf("[gossip] cannot dial node %s: %s", nodeId.String(), err.Error())
-			return
+			return false
 		}
 		nl.neighbors.PushFront(nodeId)
 		nl.connPool[nodeId] = conn
 		if nl.neighbors.Len() > nl.cap {
-			last := nl.neighbors.Back()
-			nl.connPool[last.Value.(NodeId)].Close()
-			delete(nl.connPool, last.Value.(NodeId))
-			nl.neighbors.Remove(last)
+			nl.remove(nl.neighbors.Back())
 		}
+		return true
 	}
 
 	var e *list.Element
@@ -67,12 +134,163 @@ func (nl *NeighborList) Update(nodeId NodeId) {
 			break
 		}
 	}
+	return false
+}
+
+func (nl *NeighborList) Remove(nodeId NodeId) {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	for e := nl.neighbors.Front(); e != nil; e = e.Next() {
+		if e.Value.(NodeId) == nodeId {
+			nl.remove(e)
+			return
+		}
+	}
+}
+
+func (nl *NeighborList) remove(e *list.Element) {
+	nodeId := e.Value.(NodeId)
+	nl.connPool[nodeId].Close()
+	delete(nl.connPool, nodeId)
+	delete(nl.failures, nodeId)
+	delete(nl.mismatches, nodeId)
+	delete(nl.peerInfo, nodeId)
+	nl.neighbors.Remove(e)
+}
+
+// SetPeerInfo sets what a neighbor negotiated. Its metadata is replaced
+// with newer metadata the list already knows.
+func (nl *NeighborList) SetPeerInfo(nodeId NodeId, info *PeerInfo) {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	if _, ok := nl.connPool[nodeId]; !ok {
+		return
+	}
+	if metadata, ok := nl.metadata[nodeId]; ok {
+		info.Metadata = copyEntries(metadata.Entries)
+	}
+	nl.peerInfo[nodeId] = info
+}
+
+func (nl *NeighborList) GetPeerInfo(nodeId NodeId) (*PeerInfo, bool) {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	info, ok := nl.peerInfo[nodeId]
+	return info, ok
+}
+
+// UpdateMetadata records the metadata of a node, neighbor or not, unless a
+// newer version is known. It reports whether the metadata was newer.
+func (nl *NeighborList) UpdateMetadata(metadata *NodeMetadata) bool {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	nodeId := NewNodeId(metadata.NodeId)
+	if known, ok := nl.metadata[nodeId]; ok && known.Version >= metadata.Version {
+		return false
+	}
+	if _, ok := nl.metadata[nodeId]; !ok && len(nl.metadata) >= metadataCap {
+		// forget some node that is not a neighbor
+		for other := range nl.metadata {
+			if _, ok := nl.connPool[other]; !ok {
+				delete(nl.metadata, other)
+				break
+			}
+		}
+	}
+	nl.metadata[nodeId] = &NodeMetadata{
+		NodeId:  metadata.NodeId,
+		Version: metadata.Version,
+		Entries: copyEntries(metadata.Entries),
+	}
+	if info, ok := nl.peerInfo[nodeId]; ok {
+		updated := *info
+		updated.Metadata = copyEntries(metadata.Entries)
+		nl.peerInfo[nodeId] = &updated
+	}
+	return true
+}
+
+// GetMetadata returns the latest known metadata of a node.
+func (nl *NeighborList) GetMetadata(nodeId NodeId) (map[string]string, bool) {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	metadata, ok := nl.metadata[nodeId]
+	if !ok {
+		return nil, false
+	}
+	return copyEntries(metadata.Entries), true
+}
+
+// GetMetadataVersions returns the known metadata of the given nodes.
+func (nl *NeighborList) GetMetadataVersions(nodeIds []NodeId) []*NodeMetadata {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	ret := make([]*NodeMetadata, 0, len(nodeIds))
+	for _, nodeId := range nodeIds {
+		if metadata, ok := nl.metadata[nodeId]; ok {
+			ret = append(ret, metadata)
+		}
+	}
+	return ret
+}
+
+// NodesWhere returns the nodes with known metadata that selector accepts.
+func (nl *NeighborList) NodesWhere(selector func(NodeId, map[string]string) bool) []NodeId {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	var ret []NodeId
+	for nodeId, metadata := range nl.metadata {
+		if selector(nodeId, metadata.Entries) {
+			ret = append(ret, nodeId)
+		}
+	}
+	return ret
+}
+
+// SampleNodeIdMatching samples neighbors whose metadata has all key/value
+// pairs of filter.
+func (nl *NeighborList) SampleNodeIdMatching(num int, filter map[string]string) []NodeId {
+	return nl.SampleNodeIdWhere(num, func(nodeId NodeId, info *PeerInfo) bool {
+		return info != nil && matchEntries(info.Metadata, filter)
+	})
 }
 
 func (nl *NeighborList) Len() int {
 	return nl.neighbors.Len()
 }
 
+// LenReachable counts the neighbors whose last call did not fail.
+func (nl *NeighborList) LenReachable() int {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	num := 0
+	for nodeId := range nl.connPool {
+		if nl.failures[nodeId] == 0 {
+			num++
+		}
+	}
+	return num
+}
+
+func (nl *NeighborList) MarkReachable(nodeId NodeId) {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	delete(nl.failures, nodeId)
+	delete(nl.mismatches, nodeId)
+}
+
+// AddMismatch records that nodeId reported a different topic, and returns how
+// many times in a row it did.
+func (nl *NeighborList) AddMismatch(nodeId NodeId) int {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	if _, ok := nl.connPool[nodeId]; !ok {
+		return 0
+	}
+	nl.mismatches[nodeId]++
+	return nl.mismatches[nodeId]
+}
+
 func (nl *NeighborList) SampleIdString(num int) []string {
 	nl.lock.RLock()
 	defer nl.lock.RUnlock()
@@ -97,6 +315,29 @@ func (nl *NeighborList) SampleIdString(num int) []string {
 	return samples
 }
 
+// SampleNodeIdWhere samples among the neighbors for which accept is true.
+// accept gets the peer info of each neighbor, or nil before its handshake,
+// and must not call back into the list.
+func (nl *NeighborList) SampleNodeIdWhere(num int, accept func(NodeId, *PeerInfo) bool) []NodeId {
+	nl.lock.RLock()
+	defer nl.lock.RUnlock()
+	candidates := make([]NodeId, 0, nl.neighbors.Len())
+	for e := nl.neighbors.Front(); e != nil; e = e.Next() {
+		if accept(e.Value.(NodeId), nl.peerInfo[e.Value.(NodeId)]) {
+			candidates = append(candidates, e.Value.(NodeId))
+		}
+	}
+	if num > len(candidates) {
+		num = len(candidates)
+	}
+	samples := make([]NodeId, num)
+	randIndex := rand.Perm(len(candidates))[0:num]
+	for i := range randIndex {
+		samples[i] = candidates[randIndex[i]]
+	}
+	return samples
+}
+
 func (nl *NeighborList) SampleNodeId(num int) []NodeId {
 	nl.lock.RLock()
 	defer nl.lock.RUnlock()
@@ -126,15 +367,15 @@ func (nl *NeighborList) Reconnect(nodeId NodeId) {
 	defer nl.lock.Unlock()
 	for e := nl.neighbors.Front(); e != nil; e = e.Next() {
 		if e.Value.(NodeId) == nodeId {
+			nl.failures[nodeId]++
 			nl.connPool[nodeId].Close()
-			var err error
-			nl.connPool[nodeId], err = nodeId.Dial()
+			conn, err := nl.dial(nodeId)
 			if err != nil {
 				log.Printf("[gossip] Cannot dial node %s: %s", nodeId.String(), err.Error())
-				nl.neighbors.Remove(e)
-				delete(nl.connPool, nodeId)
+				nl.remove(e)
 				return
 			}
+			nl.connPool[nodeId] = conn
 			nl.neighbors.MoveToBack(e)
 			break
 		}
@@ -168,3 +409,17 @@ func (nl *NeighborList) GetConn(nodeId NodeId) (*grpc.ClientConn, error) {
 		return nil, errors.New("conn not found")
 	}
 }
+
+func (nl *NeighborList) Close() {
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	for nodeId, conn := range nl.connPool {
+		conn.Close()
+		delete(nl.connPool, nodeId)
+	}
+	nl.failures = make(map[NodeId]int)
+	nl.mismatches = make(map[NodeId]int)
+	nl.peerInfo = make(map[NodeId]*PeerInfo)
+	nl.metadata = make(map[NodeId]*NodeMetadata)
+	nl.neighbors.Init()
+}
@@ -2,11 +2,10 @@ package gossip
 
 import (
 	context "context"
-	"errors"
-	"fmt"
 	"log"
 	"math/rand"
 	"net"
+	"sync"
 	"time"
 
 	codes "google.golang.org/grpc/codes"
@@ -21,14 +20,18 @@ const gossipFanout = 16
 const discoveryFanout = 8
 const broadcastFanout = neighborListCap
 
+// sendTimeout only guards against peers that hang, since sends queue up
+// behind each other when a lot is gossiped at once
+const sendTimeout = time.Minute
+
 type NodeId string
 
 func NewNodeId(str string) NodeId {
 	return NodeId(str)
 }
 
-func (id NodeId) Dial() (*grpc.ClientConn, error) {
-	return grpc.Dial(string(id), grpc.WithInsecure())
+func (id NodeId) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return grpc.Dial(string(id), append([]grpc.DialOption{grpc.WithInsecure()}, opts...)...)
 }
 
 func (id NodeId) String() string {
@@ -36,163 +39,584 @@ func (id NodeId) String() string {
 }
 
 type Node struct {
-	topic     string
-	nodeId    NodeId
-	neighbors *NeighborList
-	msgFilter *Filter
-	msgChan   chan []byte
+	topic             string
+	nodeId            NodeId
+	neighbors         *NeighborList
+	msgFilter         *Filter
+	msgChan           chan []byte
+	peerStore         *PeerStore
+	grpcServer        *grpc.Server
+	closed            chan struct{}
+	closeOnce         *sync.Once
+	bootnodes         []NodeId
+	minPeers          int
+	eventChan         chan Event
+	listenAddr        string
+	addr              net.Addr
+	observed          *observedAddrs
+	features          map[string]bool
+	metadata          map[string]string
+	metadataVersion   uint64
+	validators        *validators
+	scores            *PeerScores
+	rateLimiter       *RateLimiter
+	reassembler       *Reassembler
+	maxMessageSize    int
+	chunkSize         int
+	lazyThresholds    map[string]int
+	cache             *MessageCache
+	fetches           *fetches
+	codec             string
+	compressThreshold int
+	orderer           *orderer
+	causal            *causal
+	bracha            *bracha
+	acks              *ackTracker
+	ackRoutes         *ackRoutes
+	directs           *directs
+	channels          *channels
+	size              *sizeEstimator
+	aggregates        *aggregates
+	syncables         *syncables
+	msgLog            *MessageLog
+	adaptiveFanout    bool
+	queries           *queries
+	reorderOnce       *sync.Once
+	epoch             uint64
+	seq               uint64
+	lock              *sync.Mutex
 }
 
 func New(nodeId NodeId, topic string) *Node {
 	node := &Node{
-		topic:     topic,
-		nodeId:    nodeId,
-		neighbors: NewNeighborList(neighborListCap),
-		msgChan:   make(chan []byte, bufferCap),
-		msgFilter: NewFilter(60),
+		topic:          topic,
+		nodeId:         nodeId,
+		neighbors:      NewNeighborList(neighborListCap),
+		msgChan:        make(chan []byte, bufferCap),
+		msgFilter:      NewFilter(60),
+		minPeers:       defaultMinPeers,
+		eventChan:      make(chan Event, eventBufferCap),
+		observed:       newObservedAddrs(),
+		metadata:       make(map[string]string),
+		validators:     newValidators(),
+		scores:         NewPeerScores(DefaultScoreParams()),
+		reassembler:    NewReassembler(reassemblyTimeout, reassemblyMessages*defaultMaxMessageSize),
+		maxMessageSize: defaultMaxMessageSize,
+		chunkSize:      defaultChunkSize,
+		lazyThresholds: make(map[string]int),
+		cache:          NewMessageCache(messageCacheTTL, messageCacheBytes),
+		fetches:        newFetches(),
+		orderer:        newOrderer(),
+		causal:         newCausal(),
+		bracha:         newBracha(),
+		acks:           newAckTracker(),
+		ackRoutes:      newAckRoutes(),
+		directs:        newDirects(),
+		channels:       newChannels(),
+		size:           newSizeEstimator(),
+		aggregates:     newAggregates(),
+		syncables:      newSyncables(),
+		queries:        newQueries(),
+		reorderOnce:    &sync.Once{},
+		epoch:          uint64(time.Now().UnixNano()),
+		closed:         make(chan struct{}),
+		closeOnce:      &sync.Once{},
+		lock:           &sync.Mutex{},
 	}
 	node.neighbors.AddBlackList(nodeId)
+	node.neighbors.SetMaxRecvMsgSize(defaultMaxMessageSize + rpcOverhead)
+	node.setFeatures(defaultFeatures())
+	node.registerQueries()
+	node.handleChannel(metadataChannel, node.receiveMetadata)
 	return node
 }
 
 func (node *Node) Listen() error {
-	lis, err := net.Listen("tcp", node.nodeId.String())
+	lis, err := node.bind()
 	if err != nil {
-		return errors.New(fmt.Sprintf("[gossip] Cannot listen on %s: %s", node.nodeId.String(), err.Error()))
+		return err
 	}
-	grpcServer := grpc.NewServer()
+	node.serve(lis)
+	return nil
+}
+
+// Start binds the listening address and serves in the background, so that
+// the bound address is known as soon as it returns.
+func (node *Node) Start() error {
+	lis, err := node.bind()
+	if err != nil {
+		return err
+	}
+	go node.serve(lis)
+	return nil
+}
+
+func (node *Node) serve(lis net.Listener) {
+	maxSize, _ := node.sizeLimits()
+	grpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(maxSize + rpcOverhead))
 	RegisterGossipServer(grpcServer, node)
+	node.lock.Lock()
+	node.grpcServer = grpcServer
+	node.lock.Unlock()
 	grpcServer.Serve(lis)
+}
+
+func (node *Node) NodeId() NodeId {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.nodeId
+}
+
+// SetPeerStore makes the node remember its neighbors in the file at path, so
+// that a restarted node can rejoin even if its bootnodes are gone. It must be
+// called before Join.
+func (node *Node) SetPeerStore(path string) error {
+	peerStore := NewPeerStore(path)
+	if err := peerStore.Load(); err != nil {
+		return err
+	}
+	node.peerStore = peerStore
 	return nil
 }
 
+func (node *Node) savePeerStore() {
+	if node.peerStore == nil {
+		return
+	}
+	node.peerStore.Seen(node.neighbors.GetNeighborsId())
+	if err := node.peerStore.Save(); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func (node *Node) recordSuccess(nodeId NodeId) {
+	node.neighbors.MarkReachable(nodeId)
+	if node.peerStore != nil {
+		node.peerStore.RecordSuccess(nodeId)
+	}
+}
+
+func (node *Node) recordFailure(nodeId NodeId) {
+	if node.peerStore != nil {
+		node.peerStore.RecordFailure(nodeId)
+	}
+}
+
+// sleep waits for the given duration and reports false if the node was closed
+// in the meantime.
+func (node *Node) sleep(duration time.Duration) bool {
+	select {
+	case <-node.closed:
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
+
+func (node *Node) Close() {
+	node.closeOnce.Do(func() {
+		close(node.closed)
+		node.lock.Lock()
+		if node.grpcServer != nil {
+			node.grpcServer.Stop()
+		}
+		node.lock.Unlock()
+		node.savePeerStore()
+		node.neighbors.Close()
+		node.bracha.close()
+		if node.msgLog != nil {
+			node.msgLog.Close()
+		}
+	})
+}
+
 func (node *Node) Register(grpcServer *grpc.Server) {
 	RegisterGossipServer(grpcServer, node)
 }
 
 func (node *Node) GetPeers(ctx context.Context, req *NeighborReq) (*NeighborRes, error) {
 	if req.Topic != node.topic {
-		return nil, status.Errorf(codes.NotFound, "[From %s] topic does not match", node.nodeId.String())
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
 	}
 	nodeId := NewNodeId(req.NodeId)
-	node.neighbors.Update(nodeId)
+	if node.banned(nodeId, caller(ctx, nodeId)) {
+		return nil, status.Errorf(codes.PermissionDenied, "[From %s] %s", node.NodeId().String(), ReasonBanned)
+	}
+	node.addPeer(nodeId)
+	if req.Metadata != nil && req.Metadata.NodeId == req.NodeId {
+		node.learnMetadata(req.Metadata)
+	}
 	samples := node.neighbors.SampleIdString(int(req.MaxNum))
 	res := &NeighborRes{
-		Topic:     node.topic,
-		NodeId:    node.nodeId.String(),
-		Neighbors: samples,
+		Topic:        node.topic,
+		NodeId:       node.NodeId().String(),
+		Neighbors:    samples,
+		ObservedAddr: observedAddr(ctx, nodeId),
+		Metadata:     []*NodeMetadata{node.ownMetadata()},
 	}
 	return res, nil
 }
 
-func (node *Node) SendData(ctx context.Context, data *GossipData) (*Empty, error) {
+func (node *Node) SendData(ctx context.Context, data *GossipData) (*SendDataRes, error) {
 	if data.Topic != node.topic {
-		return nil, status.Errorf(codes.NotFound, "[From %s] topic does not match", node.nodeId.String())
+		return &SendDataRes{Status: SendDataRes_REJECTED, Reason: ReasonTopicMismatch}, nil
 	}
 	nodeId := NewNodeId(data.NodeId)
-	node.neighbors.Update(nodeId)
+	// the sender is whoever the call came from, not whoever it claims to be
+	if from := caller(ctx, data.From()); from != data.From() {
+		data = data.Relay(from)
+	}
+	if node.banned(data.From(), nodeId) {
+		return &SendDataRes{Status: SendDataRes_REJECTED, Reason: ReasonBanned}, nil
+	}
+	node.addPeer(nodeId)
 
-	// check redundancy and store in buffer
-	if !node.msgFilter.Check(data.Hash()) {
-		return nil, status.Errorf(codes.NotFound, "[From %s] already received the same message", node.nodeId.String())
+	if rateLimiter := node.getRateLimiter(); rateLimiter != nil && !rateLimiter.Allow(connection(ctx, data.From()), nodeId, len(data.Payload)) {
+		return node.rateLimited(rateLimiter.Action(), data.From())
+	}
+	if reason := node.checkSize(data); reason != "" {
+		node.score(data.From(), ScoreInvalidMessage)
+		return &SendDataRes{Status: SendDataRes_REJECTED, Reason: reason}, nil
 	}
-	node.msgChan <- data.Payload
+	if data.Announce {
+		return node.announced(data), nil
+	}
+	return node.receive(data), nil
+}
+
+// receive handles a message that passed the transport checks.
+func (node *Node) receive(data *GossipData) *SendDataRes {
+	// check redundancy and store in buffer. Fragments are only marked as
+	// seen once their message checks out, so that a forged fragment cannot
+	// get the genuine ones refused.
+	parts := []*GossipData{data}
+	if data.Fragment == nil {
+		if !node.msgFilter.Check(data.Hash()) {
+			node.score(data.From(), ScoreDuplicate)
+			return &SendDataRes{Status: SendDataRes_DUPLICATE}
+		}
+	} else {
+		// fragments are held back until the whole message can be validated
+		if node.msgFilter.Has(data.Hash()) {
+			node.score(data.From(), ScoreDuplicate)
+			return &SendDataRes{Status: SendDataRes_DUPLICATE}
+		}
+		whole, fragments, fresh := node.reassembler.Add(data)
+		if !fresh {
+			node.score(data.From(), ScoreDuplicate)
+			return &SendDataRes{Status: SendDataRes_DUPLICATE}
+		}
+		if whole == nil {
+			return &SendDataRes{Status: SendDataRes_NEW}
+		}
+		for i := range fragments {
+			node.msgFilter.Check(fragments[i].Hash())
+		}
+		data, parts = whole, fragments
+	}
+	if node.validators.isAsync() {
+		go node.accept(data, parts)
+		return &SendDataRes{Status: SendDataRes_NEW}
+	}
+	return node.accept(data, parts)
+}
+
+// accept validates a new message, then delivers it and forwards its parts.
+func (node *Node) accept(data *GossipData, parts []*GossipData) *SendDataRes {
+	from := data.From()
+	plain, err := node.decompress(data)
+	if err != nil {
+		log.Printf("[gossip] Cannot decode data from %s: %s", from.String(), err.Error())
+		node.score(from, ScoreInvalidMessage)
+		node.pass(data)
+		return &SendDataRes{Status: SendDataRes_REJECTED, Reason: ReasonUndecodable}
+	}
+	// the same message may arrive in another form too, whole or in fragments
+	if !node.msgFilter.Check(deliveredKey(plain)) {
+		return &SendDataRes{Status: SendDataRes_DUPLICATE}
+	}
+	switch node.validators.validate(from, plain) {
+	case ValidationReject:
+		log.Printf("[gossip] Rejected data from %s", from.String())
+		node.score(from, ScoreInvalidMessage)
+		node.pass(plain)
+		return &SendDataRes{Status: SendDataRes_REJECTED, Reason: ReasonValidationFailed}
+	case ValidationIgnore:
+		node.pass(plain)
+		return &SendDataRes{Status: SendDataRes_IGNORED, Reason: ReasonValidationIgnored}
+	}
+	node.score(from, ScoreFirstDelivery)
+	if len(parts) == 1 {
+		node.cache.Add(data)
+	}
+	node.routeAcks(data)
+	node.deliver(plain)
 
 	//gossip to other nodes
-	node.gossipToPeers(data, gossipFanout)
-	return &Empty{}, nil
+	node.gossipToPeers(plain, data, parts, node.relayFanout())
+	return &SendDataRes{Status: SendDataRes_NEW}
+}
+
+// deliveredKey identifies a message in the filter by its plain payload, so
+// that it is delivered once whatever form it arrives in.
+func deliveredKey(plain *GossipData) string {
+	return "delivered/" + plain.Hash()
 }
 
-func (node *Node) gossipToPeers(data *GossipData, fanout int) {
-	nodeIds := node.neighbors.SampleNodeId(fanout)
+// gossipToPeers sends the parts of data to up to fanout peers in the
+// background. Peers that cannot reassemble fragments get data whole, and
+// peers that do not know its codec get it as plain, with its payload
+// decompressed. It returns the number of peers, and a channel that receives
+// whether each of them took the message.
+func (node *Node) gossipToPeers(plain *GossipData, data *GossipData, parts []*GossipData, fanout int) (int, <-chan bool) {
+	relayed := make([]*GossipData, len(parts))
+	for i := range parts {
+		relayed[i] = parts[i].Relay(node.NodeId())
+	}
+	whole := []*GossipData{data.Relay(node.NodeId())}
+	var plainWhole, plainParts []*GossipData
+	if data.Codec != "" {
+		plainWhole = []*GossipData{plain.Relay(node.NodeId())}
+		for _, part := range node.split(plain) {
+			plainParts = append(plainParts, part.Relay(node.NodeId()))
+		}
+	}
+	var announced []*GossipData
+	if len(parts) == 1 && node.isLazy(parts[0]) {
+		announced = []*GossipData{announcement(relayed[0])}
+	}
+	nodeIds := node.samplePeers(fanout, data)
+	results := make(chan bool, len(nodeIds))
 	for i := range nodeIds {
 		go func(nodeId NodeId) {
+			ok := false
+			defer func() {
+				results <- ok
+			}()
 			conn, err := node.neighbors.GetConn(nodeId)
 			if err != nil {
 				log.Printf("[gossip] Connection to %s is closed", nodeId.String())
 				return
 			}
 			client := NewGossipClient(conn)
-			_, err = client.SendData(context.Background(), data)
-			if err != nil && status.Convert(err).Code() != codes.NotFound {
-				log.Printf("[gossip] Cannot send data to node %s: %s", nodeId.String(), err.Error())
-				node.neighbors.Reconnect(nodeId)
+			info, _ := node.neighbors.GetPeerInfo(nodeId)
+			chunking := info != nil && info.Supports(FeatureChunking)
+			toSend := relayed
+			switch {
+			case data.Codec != "" && (info == nil || !info.Supports(codecFeature(data.Codec))):
+				toSend = plainWhole
+				if chunking {
+					toSend = plainParts
+				}
+			case len(relayed) > 1 && !chunking:
+				toSend = whole
+			case announced != nil && info != nil && info.Supports(FeatureLazyPull):
+				toSend = announced
+			}
+			for j := range toSend {
+				if !node.sendData(client, nodeId, toSend[j]) {
+					return
+				}
 			}
+			ok = true
 		}(nodeIds[i])
 	}
+	return len(nodeIds), results
+}
+
+// sendData reports whether the peer took the message, or already had it.
+func (node *Node) sendData(client GossipClient, nodeId NodeId, data *GossipData) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	res, err := client.SendData(ctx, data)
+	cancel()
+	if err != nil {
+		// peers before SendDataRes report duplicates as NotFound
+		code := status.Convert(err).Code()
+		if code == codes.NotFound {
+			return true
+		}
+		if code == codes.ResourceExhausted {
+			return false
+		}
+		log.Printf("[gossip] Cannot send data to node %s: %s", nodeId.String(), err.Error())
+		node.recordFailure(nodeId)
+		node.score(nodeId, ScoreRPCFailure)
+		node.neighbors.Reconnect(nodeId)
+		return false
+	}
+	if res.Status == SendDataRes_REJECTED {
+		node.rejected(nodeId, res.Reason)
+		return false
+	}
+	node.recordSuccess(nodeId)
+	// an ignored message did not get through, but that is no fault of ours
+	return res.Status != SendDataRes_IGNORED
+}
+
+// understands reports whether a peer with the negotiated features in info
+// can handle data. info is nil before the handshake.
+func understands(info *PeerInfo, data *GossipData) bool {
+	if data.Channel != "" && (info == nil || !info.Supports(FeatureChannels)) {
+		return false
+	}
+	if data.Selector != "" && (info == nil || !info.Supports(FeatureSelectors)) {
+		return false
+	}
+	return true
 }
 
 func (node *Node) Join(bootnodes []NodeId) error {
+	node.lock.Lock()
+	node.bootnodes = append([]NodeId(nil), bootnodes...)
+	node.lock.Unlock()
+
 	// add to neighbor list
-	for i := range bootnodes {
-		node.neighbors.Update(bootnodes[i])
-	}
+	node.bootstrap()
 
-	go func() {
-		// run discovery forever
-		for {
-			// whether not enough peers
-			if node.neighbors.Len() >= neighborListCap {
-				time.Sleep(5 * time.Second)
-				continue
-			}
+	// run discovery until closed
+	go node.discover()
+	go node.estimateSize()
+	go node.exchangeAggregates()
+	go node.syncPeriodically()
+
+	return nil
+}
+
+func (node *Node) discover() {
+	lastSave := time.Now()
+	isolation := newIsolationState()
+	for {
+		if time.Since(lastSave) >= peerStoreInterval {
+			node.savePeerStore()
+			lastSave = time.Now()
+		}
+
+		// whether too few peers to stay connected
+		node.checkIsolation(isolation)
 
-			// how many peers to ask
-			fanout := discoveryFanout
-			if fanout > node.neighbors.Len() {
-				fanout = node.neighbors.Len()
+		// whether not enough peers
+		if node.neighbors.Len() >= neighborListCap {
+			if !node.sleep(5 * time.Second) {
+				return
 			}
+			continue
+		}
 
-			// construct request
-			avgReqests := int(float32(neighborListCap-node.neighbors.Len()) / float32(fanout) * 1.2)
-			req := &NeighborReq{
-				Topic:  node.topic,
-				NodeId: node.nodeId.String(),
-				MaxNum: int32(avgReqests),
+		// how many peers to ask
+		fanout := discoveryFanout
+		if fanout > node.neighbors.Len() {
+			fanout = node.neighbors.Len()
+		}
+		if fanout == 0 {
+			if !node.sleep(5 * time.Second) {
+				return
 			}
+			continue
+		}
 
-			nodeIds := node.neighbors.SampleNodeId(fanout)
-			for i := range nodeIds {
-				go func(nodeId NodeId) {
-					conn, err := node.neighbors.GetConn(nodeId)
-					if err != nil {
-						log.Printf("[gossip] connection to %s is closed", nodeId.String())
-						return
-					}
-					client := NewGossipClient(conn)
-					res, err := client.GetPeers(context.Background(), req)
-					if err != nil {
-						log.Printf("[gossip] node %s cannot call GetPeer: %s", nodeId.String(), err.Error())
-						node.neighbors.Reconnect(nodeId)
+		// construct request
+		avgReqests := int(float32(neighborListCap-node.neighbors.Len()) / float32(fanout) * 1.2)
+		req := &NeighborReq{
+			Topic:    node.topic,
+			NodeId:   node.NodeId().String(),
+			MaxNum:   int32(avgReqests),
+			Metadata: node.ownMetadata(),
+		}
+
+		nodeIds := node.neighbors.SampleNodeId(fanout)
+		for i := range nodeIds {
+			go func(nodeId NodeId) {
+				conn, err := node.neighbors.GetConn(nodeId)
+				if err != nil {
+					log.Printf("[gossip] connection to %s is closed", nodeId.String())
+					return
+				}
+				client := NewGossipClient(conn)
+				res, err := client.GetPeers(context.Background(), req)
+				if err != nil {
+					log.Printf("[gossip] node %s cannot call GetPeer: %s", nodeId.String(), err.Error())
+					if isTopicMismatch(err) {
+						node.rejected(nodeId, ReasonTopicMismatch)
 						return
 					}
-					for j := range res.Neighbors {
-						node.neighbors.Update(NewNodeId(res.Neighbors[j]))
+					node.recordFailure(nodeId)
+					node.score(nodeId, ScoreRPCFailure)
+					node.neighbors.Reconnect(nodeId)
+					return
+				}
+				node.recordSuccess(nodeId)
+				node.observe(nodeId, NewNodeId(res.ObservedAddr))
+				if _, ok := node.neighbors.GetPeerInfo(nodeId); !ok {
+					node.handshake(nodeId)
+				}
+				for j := range res.Neighbors {
+					node.addPeer(NewNodeId(res.Neighbors[j]))
+				}
+				// the metadata of other nodes is only taken from them
+				for j := range res.Metadata {
+					if res.Metadata[j].NodeId == nodeId.String() {
+						node.learnMetadata(res.Metadata[j])
 					}
-				}(nodeIds[i])
-			}
-			time.Sleep(5 * time.Second)
+				}
+			}(nodeIds[i])
+		}
+		if !node.sleep(5 * time.Second) {
+			return
 		}
-	}()
+	}
+}
 
+func (node *Node) Gossip(data []byte) error {
+	gossipData, err := node.newGossipData(data)
+	if err != nil {
+		return err
+	}
+	node.publish(gossipData)
 	return nil
 }
 
-func (node *Node) Gossip(data []byte) {
+func (node *Node) newGossipData(data []byte) (*GossipData, error) {
+	if err := node.checkMessageSize(len(data)); err != nil {
+		return nil, err
+	}
 	nonce := rand.Uint64()
+	epoch, seq := node.sequence()
 	gossipData := &GossipData{
 		Topic:   node.topic,
-		NodeId:  node.nodeId.String(),
+		NodeId:  node.NodeId().String(),
 		Nonce:   nonce,
 		Payload: data,
+		Epoch:   epoch,
+		Seq:     seq,
+	}
+	gossipData.Deps = node.causal.publish(gossipData)
+	return gossipData, nil
+}
+
+// publish delivers a new message to the node itself and gossips it, with
+// the results of gossipToPeers.
+func (node *Node) publish(gossipData *GossipData) (int, <-chan bool) {
+	compressed := node.compress(gossipData)
+	parts := node.split(compressed)
+	if len(parts) == 1 {
+		node.cache.Add(parts[0])
 	}
 
 	// gossip to self
-	if node.msgFilter.Check(gossipData.Hash()) {
-		node.msgChan <- data
+	fresh := node.msgFilter.Check(deliveredKey(gossipData))
+	for i := range parts {
+		if !node.msgFilter.Check(parts[i].Hash()) {
+			fresh = false
+		}
+	}
+	if fresh && gossipData.Channel != "" {
+		node.dispatch(gossipData)
+	} else if fresh && node.selected(gossipData) {
+		node.logMessage(gossipData)
+		node.msgChan <- gossipData.Payload
 	}
 
-	node.gossipToPeers(gossipData, broadcastFanout)
+	return node.gossipToPeers(gossipData, compressed, parts, broadcastFanout)
 }
 
 func (node *Node) GetMsgChan() chan []byte {
@@ -1,11 +1,18 @@
 package gossip
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
+	"net"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/golang/protobuf/proto"
 )
 
 func TestMessages(t *testing.T) {
@@ -44,3 +51,837 @@ func TestMessages(t *testing.T) {
 		fmt.Printf("node %d complete\n", i)
 	}
 }
+
+func TestEphemeralPort(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	if bootNode.NodeId() == NewNodeId("127.0.0.1:0") || bootNode.NodeId().String() != bootNode.Addr().String() {
+		t.Fatalf("node id %s does not match bound address %s", bootNode.NodeId(), bootNode.Addr())
+	}
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	node.Gossip([]byte("hello"))
+
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != "hello" {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("message not received")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.SetFeatures([]string{"app", "boot only"})
+	bootNode.SetMetadata(map[string]string{"role": "boot"})
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetFeatures([]string{"app", "node only"})
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+
+	waitForHandshake(t, node, bootNode.NodeId())
+	if info, _ := node.GetNeighborList().GetPeerInfo(bootNode.NodeId()); info.Version != ProtocolVersion || info.Metadata["role"] != "boot" {
+		t.Errorf("unexpected peer info %+v", info)
+	}
+	// the application's features come on top of the node's own
+	if !node.PeerSupports(bootNode.NodeId(), FeatureCompression) || !node.PeerSupports(bootNode.NodeId(), FeatureChunking) {
+		t.Error("built-in features not negotiated")
+	}
+	if !node.PeerSupports(bootNode.NodeId(), "app") {
+		t.Error("application feature not negotiated")
+	}
+	if node.PeerSupports(bootNode.NodeId(), "boot only") || node.PeerSupports(bootNode.NodeId(), "node only") {
+		t.Error("negotiated a feature one side does not have")
+	}
+	if !bootNode.PeerSupports(node.NodeId(), FeatureCompression) {
+		t.Error("compression not negotiated on the responding side")
+	}
+}
+
+func TestValidator(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.AddValidator("test topic", func(ctx context.Context, from NodeId, data *GossipData) ValidationResult {
+		if string(data.Payload) == "bad" {
+			return ValidationReject
+		}
+		return ValidationAccept
+	})
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	node.Gossip([]byte("bad"))
+	node.Gossip([]byte("good"))
+
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != "good" {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for bootNode.PeerScore(node.NodeId()) >= 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("rejected message did not lower the score of its sender")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		t.Errorf("rejected message %s was delivered", msg)
+	default:
+	}
+}
+
+func TestChunking(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetChunkSize(10)
+	node.SetMaxMessageSize(1000)
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+
+	if err := node.Gossip(make([]byte, 1001)); err == nil {
+		t.Error("oversized message was published")
+	}
+	payload := []byte(strings.Repeat("0123456789", 9) + "end")
+	if err := node.Gossip(payload); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("message not received")
+	}
+}
+
+func TestReassemblyLimit(t *testing.T) {
+	sender := New(NewNodeId("127.0.0.1:9151"), "test topic")
+	sender.SetChunkSize(10)
+	data, _ := sender.newGossipData([]byte(strings.Repeat("0123456789", 10)))
+	parts := sender.split(data)
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	node.reassembler.Add(parts[0])
+	node.reassembler.Add(parts[1])
+	// lowering the largest message size lowers what is buffered for
+	// reassembly along with it
+	node.SetMaxMessageSize(4)
+	if buffered := node.reassembler.buffered; buffered > reassemblyMessages*4 {
+		t.Errorf("%d bytes of fragments buffered", buffered)
+	}
+	node.SetMaxMessageSize(1000)
+	for i := 0; i < len(parts)-1; i++ {
+		node.reassembler.Add(parts[i])
+	}
+	if buffered := node.reassembler.buffered; buffered != len(data.Payload)-10 {
+		t.Errorf("%d bytes of fragments buffered", buffered)
+	}
+}
+
+func TestChunkingFallback(t *testing.T) {
+	// a peer from before chunking
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.setFeatures(without(bootNode.Features(), FeatureChunking))
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetChunkSize(10)
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+	if node.PeerSupports(bootNode.NodeId(), FeatureChunking) {
+		t.Fatal("chunking negotiated with a peer without it")
+	}
+
+	payload := []byte(strings.Repeat("0123456789", 9) + "end")
+	if err := node.Gossip(payload); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("message not received whole")
+	}
+}
+
+func TestForgedFragment(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	sender := New(NewNodeId("127.0.0.1:9141"), "test topic")
+	sender.SetChunkSize(10)
+	payload := []byte(strings.Repeat("0123456789", 3))
+	data, _ := sender.newGossipData(payload)
+	parts := sender.split(data)
+
+	forged := *parts[0]
+	forged.Payload = []byte("9876543210")
+	node.receive(&forged)
+	for i := range parts {
+		node.receive(parts[i])
+	}
+	select {
+	case msg := <-node.GetMsgChan():
+		t.Fatalf("message with a forged fragment delivered: %s", msg)
+	default:
+	}
+
+	// the genuine fragments arrive again from another relay
+	for i := range parts {
+		node.receive(parts[i])
+	}
+	select {
+	case msg := <-node.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Errorf("unexpected message %s", msg)
+		}
+	default:
+		t.Fatal("message not reassembled from genuine fragments")
+	}
+	// the message was also sent whole
+	if res := node.receive(data); res.Status != SendDataRes_DUPLICATE {
+		t.Errorf("whole message not a duplicate: %v", res)
+	}
+	for i := range parts {
+		if res := node.receive(parts[i]); res.Status != SendDataRes_DUPLICATE {
+			t.Errorf("fragment %d not a duplicate: %v", i, res)
+		}
+	}
+	select {
+	case msg := <-node.GetMsgChan():
+		t.Errorf("message delivered twice: %s", msg)
+	default:
+	}
+}
+
+func without(features []string, feature string) []string {
+	ret := make([]string, 0, len(features))
+	for i := range features {
+		if features[i] != feature {
+			ret = append(ret, features[i])
+		}
+	}
+	return ret
+}
+
+func waitForHandshake(t *testing.T, node *Node, peer NodeId) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := node.GetNeighborList().GetPeerInfo(peer); ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("handshake not completed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLazyPull(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetLazyThreshold("test topic", 5)
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+	if !node.PeerSupports(bootNode.NodeId(), FeatureLazyPull) {
+		t.Fatal("lazy pull not negotiated")
+	}
+
+	node.Gossip([]byte("hello world"))
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != "hello world" {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestLazyPullLarge(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetLazyThreshold("test topic", 1<<20)
+	node.SetChunkSize(0)
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+
+	// beyond the 4 MB gRPC clients accept by default
+	payload := make([]byte, 6<<20)
+	rand.Read(payload)
+	if err := node.Gossip(payload); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Error("unexpected message")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("large message not pulled")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.SetCompression("unknown", 0); err == nil {
+		t.Error("unknown codec accepted")
+	}
+	if err := node.SetCompression("gzip", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+	if !node.PeerSupports(bootNode.NodeId(), codecFeature("gzip")) {
+		t.Fatal("gzip not negotiated")
+	}
+
+	payload := []byte(strings.Repeat("compressible ", 100))
+	if compressed := node.compress(&GossipData{Payload: payload}); compressed.Codec != "gzip" || len(compressed.Payload) >= len(payload) {
+		t.Fatal("payload not compressed")
+	}
+	node.Gossip(payload)
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestCompressionFallback(t *testing.T) {
+	// a peer that does not know gzip
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.setFeatures(without(bootNode.Features(), codecFeature("gzip")))
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.SetCompression("gzip", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+	if node.PeerSupports(bootNode.NodeId(), codecFeature("gzip")) {
+		t.Fatal("gzip negotiated with a peer without it")
+	}
+
+	payload := []byte(strings.Repeat("compressible ", 100))
+	node.Gossip(payload)
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		if string(msg) != string(payload) {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("compressed message not sent decompressed")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	lonely := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := lonely.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer lonely.Close()
+	if _, err := lonely.Publish(context.Background(), []byte("hello"), PublishOptions{}); err == nil {
+		t.Error("publishing without peers succeeded")
+	}
+
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	var nodes [2]*Node
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		nodes[i].Join([]NodeId{bootNode.NodeId()})
+		waitForHandshake(t, nodes[i], bootNode.NodeId())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := nodes[0].Publish(ctx, []byte("hello"), PublishOptions{MinAcks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Accepted == 0 || result.Acks < 2 || result.Coverage <= 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// nodes the message does not target relay it but do not acknowledge it
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result, err = nodes[0].Publish(ctx, []byte("hello"), PublishOptions{MinAcks: 1, Selector: "role=indexer"})
+	if err != context.DeadlineExceeded || result.Accepted == 0 || result.Acks != 0 {
+		t.Errorf("unexpected result %+v, %v", result, err)
+	}
+}
+
+func TestPublishHungPeer(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	peer := New(NewNodeId("127.0.0.1:0"), "test topic")
+	for _, n := range []*Node{node, peer} {
+		if err := n.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer n.Close()
+	}
+	node.Join([]NodeId{peer.NodeId()})
+	waitForHandshake(t, node, peer.NodeId())
+
+	// a peer that takes connections but never answers
+	hung, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hung.Close()
+	go func() {
+		for {
+			conn, err := hung.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	node.neighbors.Update(NewNodeId(hung.Addr().String()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := node.Publish(ctx, []byte("hello"), PublishOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Peers != 2 || result.Accepted != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	var nodes [2]*Node
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		nodes[i].Join([]NodeId{bootNode.NodeId()})
+		waitForHandshake(t, nodes[i], bootNode.NodeId())
+		waitForHandshake(t, bootNode, nodes[i].NodeId())
+	}
+	nodes[1].HandleDirect("", func(ctx context.Context, from NodeId, payload []byte) ([]byte, error) {
+		if from != nodes[0].NodeId() {
+			t.Errorf("request from %s", from)
+		}
+		return append([]byte("re: "), payload...), nil
+	})
+
+	// the nodes only know each other through the boot node
+	nodes[0].GetNeighborList().Remove(nodes[1].NodeId())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := nodes[0].Request(ctx, nodes[1].NodeId(), []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "re: hello" {
+		t.Errorf("unexpected response %s", res)
+	}
+	if _, err := nodes[1].Request(ctx, bootNode.NodeId(), []byte("hello")); err == nil {
+		t.Error("request without handler succeeded")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	var nodes [3]*Node
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		if i > 0 {
+			nodes[i].SetMetadata(map[string]string{"role": "worker"})
+		}
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		version := fmt.Sprintf("v%d", i)
+		nodes[i].HandleQuery("version", func(ctx context.Context, from NodeId, payload []byte) ([]byte, error) {
+			return []byte(version), nil
+		})
+		nodes[i].Join([]NodeId{bootNode.NodeId()})
+		waitForHandshake(t, nodes[i], bootNode.NodeId())
+		waitForHandshake(t, bootNode, nodes[i].NodeId())
+	}
+
+	result, err := nodes[0].Query(context.Background(), "version", nil, QueryOptions{
+		Filter:   map[string]string{"role": "worker"},
+		RelayAck: true,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make(map[string]bool)
+	for res := range result.Responses {
+		versions[string(res.Payload)] = true
+	}
+	if len(versions) != 2 || !versions["v1"] || !versions["v2"] {
+		t.Errorf("unexpected responses %v", versions)
+	}
+	acks := 0
+	for range result.Acks {
+		acks++
+	}
+	if acks != 4 {
+		t.Errorf("%d acks instead of 4", acks)
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		t.Errorf("query delivered as message %s", msg)
+	default:
+	}
+
+	result, err = nodes[0].Query(context.Background(), "version", nil, QueryOptions{MaxResponses: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := 0
+	for range result.Responses {
+		responses++
+	}
+	if responses != 1 {
+		t.Errorf("%d responses instead of 1", responses)
+	}
+}
+
+func TestQueryResponsesKept(t *testing.T) {
+	q := newQueries()
+	count := queryResponseBuffer + 10
+
+	// without a limit, responses wait for the caller
+	pending := q.add(0)
+	for i := 0; i < count; i++ {
+		go q.respond(&QueryRes{Id: pending.id, NodeId: fmt.Sprintf("127.0.0.1:%d", 9200+i)})
+	}
+	for i := 0; i < count; i++ {
+		select {
+		case <-pending.responses:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d responses instead of %d", i, count)
+		}
+	}
+	q.done(pending.id)
+
+	// with one, all of them are kept
+	pending = q.add(count)
+	for i := 0; i < count; i++ {
+		q.respond(&QueryRes{Id: pending.id, NodeId: fmt.Sprintf("127.0.0.1:%d", 9200+i)})
+	}
+	<-pending.finished
+	q.done(pending.id)
+	responses := 0
+	for range pending.responses {
+		responses++
+	}
+	if responses != count {
+		t.Errorf("%d responses instead of %d", responses, count)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.SetMetadata(map[string]string{"role": "boot"})
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	node.Join([]NodeId{bootNode.NodeId()})
+	waitForHandshake(t, node, bootNode.NodeId())
+	waitForHandshake(t, bootNode, node.NodeId())
+
+	if metadata, _ := node.PeerMetadata(bootNode.NodeId()); metadata["role"] != "boot" {
+		t.Errorf("unexpected metadata %v", metadata)
+	}
+	if nodeIds := node.GetNeighborList().SampleNodeIdMatching(10, map[string]string{"role": "boot"}); len(nodeIds) != 1 {
+		t.Errorf("unexpected matching neighbors %v", nodeIds)
+	}
+
+	// changes are gossiped
+	bootNode.SetMetadata(map[string]string{"role": "indexer"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if metadata, _ := node.PeerMetadata(bootNode.NodeId()); metadata["role"] == "indexer" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("metadata change not received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info, _ := node.GetNeighborList().GetPeerInfo(bootNode.NodeId()); info.Metadata["role"] != "indexer" {
+		t.Errorf("peer info has stale metadata %v", info.Metadata)
+	}
+	// older versions are ignored
+	if node.GetNeighborList().UpdateMetadata(&NodeMetadata{NodeId: bootNode.NodeId().String(), Version: 1}) {
+		t.Error("older metadata replaced newer")
+	}
+
+	// metadata relayed by other nodes is not taken
+	relayed := func(owner NodeId) *GossipData {
+		payload, err := proto.Marshal(&NodeMetadata{NodeId: owner.String(), Version: math.MaxUint64, Entries: map[string]string{"role": "forged"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &GossipData{NodeId: owner.String(), Sender: "127.0.0.1:1", Payload: payload}
+	}
+	stranger := NewNodeId("127.0.0.1:2")
+	node.receiveMetadata(relayed(bootNode.NodeId()))
+	node.receiveMetadata(relayed(stranger))
+	time.Sleep(100 * time.Millisecond)
+	if metadata, _ := node.PeerMetadata(bootNode.NodeId()); metadata["role"] != "indexer" {
+		t.Errorf("relayed metadata taken: %v", metadata)
+	}
+	if metadata, ok := node.PeerMetadata(stranger); ok {
+		t.Errorf("relayed metadata taken: %v", metadata)
+	}
+	bootNode.GetNeighborList().UpdateMetadata(&NodeMetadata{NodeId: stranger.String(), Version: 1})
+	res, err := bootNode.GetPeers(context.Background(), &NeighborReq{Topic: "test topic", NodeId: node.NodeId().String(), MaxNum: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Metadata) != 1 || res.Metadata[0].NodeId != bootNode.NodeId().String() {
+		t.Errorf("peer exchange passed on third party metadata %v", res.Metadata)
+	}
+}
+
+func TestGossipTo(t *testing.T) {
+	bootNode := New(NewNodeId("127.0.0.1:0"), "test topic")
+	bootNode.SetMetadata(map[string]string{"role": "relay"})
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	var nodes [2]*Node
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		nodes[i].SetMetadata(map[string]string{"role": "indexer", "zone": fmt.Sprintf("z%d", i)})
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		nodes[i].Join([]NodeId{bootNode.NodeId()})
+		waitForHandshake(t, nodes[i], bootNode.NodeId())
+		waitForHandshake(t, bootNode, nodes[i].NodeId())
+	}
+
+	if err := nodes[0].GossipTo("zone in (", []byte("hello")); err == nil {
+		t.Error("invalid selector accepted")
+	}
+	if err := nodes[0].GossipTo("role=indexer,zone in (z1)", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-nodes[1].GetMsgChan():
+		if string(msg) != "hello" {
+			t.Errorf("unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+	select {
+	case msg := <-bootNode.GetMsgChan():
+		t.Errorf("relay delivered %s", msg)
+	case msg := <-nodes[0].GetMsgChan():
+		t.Errorf("publisher delivered %s", msg)
+	default:
+	}
+}
+
+// mapSyncable is a Syncable whose values are their own digests.
+type mapSyncable struct {
+	values map[string][]byte
+	lock   *sync.Mutex
+}
+
+func newMapSyncable() *mapSyncable {
+	return &mapSyncable{values: make(map[string][]byte), lock: &sync.Mutex{}}
+}
+
+func (m *mapSyncable) Digests() map[string][]byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	digests := make(map[string][]byte, len(m.values))
+	for key, value := range m.values {
+		digests[key] = value
+	}
+	return digests
+}
+
+func (m *mapSyncable) Value(key string) ([]byte, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Merge keeps the larger value.
+func (m *mapSyncable) Merge(key string, value []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if string(value) > string(m.values[key]) {
+		m.values[key] = value
+	}
+	return nil
+}
+
+func TestMerkleSync(t *testing.T) {
+	var nodes [2]*Node
+	var datasets [2]*mapSyncable
+	for i := range nodes {
+		nodes[i] = New(NewNodeId("127.0.0.1:0"), "test topic")
+		if err := nodes[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].Close()
+		datasets[i] = newMapSyncable()
+		nodes[i].AddSyncable("dataset", datasets[i])
+	}
+	nodes[1].Join([]NodeId{nodes[0].NodeId()})
+	waitForHandshake(t, nodes[1], nodes[0].NodeId())
+
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		datasets[0].values[key] = []byte("a")
+		datasets[1].values[key] = []byte("a")
+	}
+	datasets[0].values["only0"] = []byte("a")
+	datasets[1].values["only1"] = []byte("a")
+	datasets[1].values["7"] = []byte("b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := nodes[1].SyncWith(ctx, nodes[0].NodeId(), "dataset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pulled != 2 || result.Pushed != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	for i := range datasets {
+		if len(datasets[i].values) != 1002 || string(datasets[i].values["7"]) != "b" {
+			t.Errorf("dataset %d did not converge", i)
+		}
+	}
+	// the trees are equal now
+	if result, err := nodes[1].SyncWith(ctx, nodes[0].NodeId(), "dataset"); err != nil || result.Pulled+result.Pushed != 0 {
+		t.Errorf("unexpected result %+v, %v", result, err)
+	}
+	if _, err := nodes[1].SyncWith(ctx, nodes[0].NodeId(), "unknown"); err == nil {
+		t.Error("synced an unknown dataset")
+	}
+}
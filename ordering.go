@@ -0,0 +1,374 @@
+package gossip
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Ordering int
+
+const (
+	// OrderingNone delivers messages as soon as they arrive.
+	OrderingNone Ordering = iota
+	// OrderingFIFO delivers the messages of every origin in the order it
+	// published them.
+	OrderingFIFO
+)
+
+// GapPolicy decides what happens to a gap in the sequence of an origin that
+// could not be recovered within the gap timeout.
+type GapPolicy int
+
+const (
+	// GapSkip gives up on the missing messages and delivers what follows.
+	GapSkip GapPolicy = iota
+	// GapWait keeps waiting for the missing messages, until the reorder
+	// buffer of the origin is full.
+	GapWait
+)
+
+const defaultGapTimeout = 5 * time.Second
+const gapPullDelay = 500 * time.Millisecond
+const gapPullInterval = time.Second
+const gapPullPeers = 3
+const maxGapPulls = 32
+const reorderBufferCap = 1024
+const reorderCheckInterval = 100 * time.Millisecond
+const streamIdleTimeout = 10 * time.Minute
+
+// epochs are the start times of origins, so messages from epochs further
+// ahead of the clock than maxEpochSkew are refused
+const maxEpochSkew = time.Minute
+
+const ReasonFutureEpoch = "epoch ahead of the clock"
+
+// a stream first seen after its start waits that long for earlier messages
+// that were reordered on the way, before it delivers anything
+const streamHoldDown = 500 * time.Millisecond
+
+// SetOrdering sets how received messages are delivered. With OrderingFIFO or
+// OrderingCausal, messages that arrive ahead of their predecessors are
+// buffered, and the missing ones are pulled from neighbors. Origins first
+// seen in the middle of their sequence are held back briefly, in case their
+// earlier messages arrive late. Messages that validators reject or ignore
+// do not hold up those after them. Relaying is not delayed.
+func (node *Node) SetOrdering(ordering Ordering) {
+	node.orderer.setOrdering(ordering)
+	node.causal.setEnabled(ordering == OrderingCausal)
+	if ordering != OrderingNone {
+		node.reorderOnce.Do(func() {
+			go node.reorder()
+		})
+	}
+}
+
+// SetGapPolicy sets how long a gap is tried to be recovered, and what to do
+// when that fails.
+func (node *Node) SetGapPolicy(policy GapPolicy, timeout time.Duration) {
+	node.orderer.setGapPolicy(policy, timeout)
+	node.causal.setGapPolicy(policy, timeout)
+}
+
+// sequence numbers the next message published by the node.
+func (node *Node) sequence() (uint64, uint64) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.seq++
+	return node.epoch, node.seq
+}
+
+// deliver hands a validated message to the subscriber, in order if so
+// configured.
+func (node *Node) deliver(data *GossipData) {
+	if data.Channel != "" {
+		node.dispatch(data)
+	} else if data.Seq == 0 || !node.causal.add(data, node.release) && !node.orderer.add(data, node.release) {
+		node.release(data)
+	}
+}
+
+// pass takes the place of a message that will not be delivered, because it
+// was rejected or ignored, in the sequence of its origin, so that the
+// messages after it are not held up.
+func (node *Node) pass(data *GossipData) {
+	if data.Channel == "" && data.Seq != 0 && !node.causal.pass(data, node.release) {
+		node.orderer.pass(data, node.release)
+	}
+}
+
+// release delivers a message that is due to the subscriber, unless it
+// targets other nodes. Targeted messages are ordered all the same, so that
+// they do not leave gaps.
+func (node *Node) release(data *GossipData) {
+	if node.selected(data) {
+		node.logMessage(data)
+		node.msgChan <- data.Payload
+		node.acknowledge(data)
+	}
+}
+
+// reorder resolves gaps in the sequences of origins until the node is
+// closed.
+func (node *Node) reorder() {
+	for node.sleep(reorderCheckInterval) {
+		gaps := append(node.orderer.check(node.release), node.causal.check(node.release)...)
+		for _, gap := range gaps {
+			go node.pullGap(gap)
+		}
+	}
+}
+
+// pullGap fetches missing messages from some neighbors. Fetched messages are
+// received like any other.
+func (node *Node) pullGap(gap gap) {
+	for _, nodeId := range node.neighbors.SampleNodeId(gapPullPeers) {
+		conn, err := node.neighbors.GetConn(nodeId)
+		if err != nil {
+			continue
+		}
+		client := NewGossipClient(conn)
+		var missing []uint64
+		for _, seq := range gap.seqs {
+			req := &FetchReq{
+				Topic:  node.topic,
+				Origin: gap.origin,
+				Epoch:  gap.epoch,
+				Seq:    seq,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+			data, err := client.FetchData(ctx, req)
+			cancel()
+			if err != nil || data.NodeId != gap.origin || data.Epoch != gap.epoch || data.Seq != seq || data.Fragment != nil || node.checkSize(data) != "" {
+				missing = append(missing, seq)
+				continue
+			}
+			data.Sender = nodeId.String()
+			node.receive(data)
+		}
+		if len(missing) == 0 {
+			return
+		}
+		gap.seqs = missing
+	}
+}
+
+type gap struct {
+	origin string
+	epoch  uint64
+	seqs   []uint64
+}
+
+type stream struct {
+	origin  string
+	epoch   uint64
+	next    uint64
+	pending map[uint64]*GossipData
+	// passed are the sequence numbers taken by messages that were not
+	// delivered. Should another message with one of them turn up, it was the
+	// genuine one and is delivered late.
+	passed    map[uint64]bool
+	holdUntil time.Time
+	gapSince  time.Time
+	lastPull  time.Time
+	lastSeen  time.Time
+}
+
+// orderer buffers the messages of every origin until they can be delivered
+// in sequence. A restarted origin starts a new epoch, which is ordered as a
+// stream of its own, so that a message claiming a later epoch does not cut
+// off the one that is running.
+type orderer struct {
+	ordering   Ordering
+	policy     GapPolicy
+	gapTimeout time.Duration
+	streams    map[string]*stream
+	lock       *sync.Mutex
+}
+
+func newOrderer() *orderer {
+	return &orderer{
+		gapTimeout: defaultGapTimeout,
+		streams:    make(map[string]*stream),
+		lock:       &sync.Mutex{},
+	}
+}
+
+func (o *orderer) setOrdering(ordering Ordering) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.ordering = ordering
+}
+
+func (o *orderer) setGapPolicy(policy GapPolicy, timeout time.Duration) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.policy = policy
+	o.gapTimeout = timeout
+}
+
+// add delivers data and whatever it unblocks to msgChan, or buffers it. It
+// returns false if ordering is off. Delivery happens under the lock, so that
+// concurrent receivers cannot reorder messages again.
+func (o *orderer) add(data *GossipData, emit func(*GossipData)) bool {
+	return o.put(data, data, emit)
+}
+
+// pass advances the sequence over data without delivering it.
+func (o *orderer) pass(data *GossipData, emit func(*GossipData)) bool {
+	return o.put(data, nil, emit)
+}
+
+// put buffers value, or nil for a passed message, at the place of data.
+func (o *orderer) put(data *GossipData, value *GossipData, emit func(*GossipData)) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.ordering != OrderingFIFO {
+		return false
+	}
+	current := time.Now()
+	key := sequenceKey(data.NodeId, data.Epoch, 0)
+	s, ok := o.streams[key]
+	if !ok {
+		// the lowest message seen from an origin within the hold-down starts
+		// its sequence, unless the first one is its very first
+		s = &stream{
+			origin:  data.NodeId,
+			epoch:   data.Epoch,
+			next:    data.Seq,
+			pending: make(map[uint64]*GossipData),
+			passed:  make(map[uint64]bool),
+		}
+		if data.Seq > 1 {
+			s.holdUntil = current.Add(streamHoldDown)
+		}
+		o.streams[key] = s
+	}
+	s.lastSeen = current
+	if data.Seq < s.next && current.Before(s.holdUntil) {
+		s.next = data.Seq
+	}
+	if data.Seq < s.next {
+		if value != nil && s.passed[data.Seq] {
+			delete(s.passed, data.Seq)
+			emit(value)
+		}
+		// delivered or given up on
+		return true
+	}
+	if known, ok := s.pending[data.Seq]; ok {
+		if known == nil && value != nil {
+			s.pending[data.Seq] = value
+			delete(s.passed, data.Seq)
+		}
+		return true
+	}
+	if value == nil {
+		if len(s.passed) >= reorderBufferCap {
+			s.passed = make(map[uint64]bool)
+		}
+		s.passed[data.Seq] = true
+	}
+	s.pending[data.Seq] = value
+	if current.Before(s.holdUntil) && len(s.pending) <= reorderBufferCap {
+		return true
+	}
+	o.flush(s, emit)
+	for len(s.pending) > reorderBufferCap {
+		o.skip(s, emit)
+	}
+	return true
+}
+
+// flush delivers the buffered messages that are next in sequence.
+func (o *orderer) flush(s *stream, emit func(*GossipData)) {
+	for {
+		data, ok := s.pending[s.next]
+		if !ok {
+			break
+		}
+		delete(s.pending, s.next)
+		s.next++
+		s.gapSince = time.Time{}
+		if data != nil {
+			emit(data)
+		}
+	}
+	if len(s.pending) > 0 && s.gapSince.IsZero() {
+		s.gapSince = time.Now()
+	}
+}
+
+// skip gives up on the current gap of s.
+func (o *orderer) skip(s *stream, emit func(*GossipData)) {
+	first := uint64(0)
+	for seq := range s.pending {
+		if first == 0 || seq < first {
+			first = seq
+		}
+	}
+	s.next = first
+	s.gapSince = time.Time{}
+	o.flush(s, emit)
+}
+
+// check skips expired gaps and returns the gaps that should be pulled.
+func (o *orderer) check(emit func(*GossipData)) []gap {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	current := time.Now()
+	var gaps []gap
+	for key, s := range o.streams {
+		if !s.holdUntil.IsZero() {
+			if current.Before(s.holdUntil) {
+				continue
+			}
+			s.holdUntil = time.Time{}
+			o.flush(s, emit)
+		}
+		if current.Sub(s.lastSeen) > streamIdleTimeout {
+			// the epoch ended, what is left of it will not be completed
+			for len(s.pending) > 0 {
+				o.skip(s, emit)
+			}
+			delete(o.streams, key)
+			continue
+		}
+		if len(s.pending) == 0 {
+			continue
+		}
+		if o.policy == GapSkip && current.Sub(s.gapSince) > o.gapTimeout {
+			o.skip(s, emit)
+			continue
+		}
+		if current.Sub(s.gapSince) < gapPullDelay || current.Sub(s.lastPull) < gapPullInterval {
+			continue
+		}
+		s.lastPull = current
+		gaps = append(gaps, gap{
+			origin: s.origin,
+			epoch:  s.epoch,
+			seqs:   s.missing(),
+		})
+	}
+	return gaps
+}
+
+// missing returns the first sequence numbers absent from the buffer.
+func (s *stream) missing() []uint64 {
+	buffered := make([]uint64, 0, len(s.pending))
+	for seq := range s.pending {
+		buffered = append(buffered, seq)
+	}
+	sort.Slice(buffered, func(i, j int) bool { return buffered[i] < buffered[j] })
+	var missing []uint64
+	seq := s.next
+	for _, next := range buffered {
+		for ; seq < next && len(missing) < maxGapPulls; seq++ {
+			missing = append(missing, seq)
+		}
+		seq = next + 1
+	}
+	return missing
+}
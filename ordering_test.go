@@ -0,0 +1,199 @@
+package gossip
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func sequenced(epoch, seq uint64) *GossipData {
+	return &GossipData{
+		NodeId:  "origin",
+		Epoch:   epoch,
+		Seq:     seq,
+		Payload: []byte{byte(seq)},
+	}
+}
+
+func emitTo(msgChan chan []byte) func(*GossipData) {
+	return func(data *GossipData) {
+		msgChan <- data.Payload
+	}
+}
+
+func receivedSeqs(msgChan chan []byte) []int {
+	var seqs []int
+	for {
+		select {
+		case msg := <-msgChan:
+			seqs = append(seqs, int(msg[0]))
+		default:
+			return seqs
+		}
+	}
+}
+
+func TestOrderer(t *testing.T) {
+	msgChan := make(chan []byte, 16)
+	emit := emitTo(msgChan)
+	o := newOrderer()
+	if o.add(sequenced(1, 1), emit) {
+		t.Fatal("ordered without OrderingFIFO")
+	}
+	o.setOrdering(OrderingFIFO)
+	o.setGapPolicy(GapSkip, time.Second)
+
+	for _, seq := range []uint64{1, 3, 4, 2, 6} {
+		o.add(sequenced(1, seq), emit)
+	}
+	if seqs := receivedSeqs(msgChan); len(seqs) != 4 || seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 || seqs[3] != 4 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+	if gaps := o.check(emit); len(gaps) != 0 {
+		t.Errorf("gap pulled before the pull delay: %v", gaps)
+	}
+	o.streams[sequenceKey("origin", 1, 0)].gapSince = time.Now().Add(-gapPullDelay)
+	if gaps := o.check(emit); len(gaps) != 1 || len(gaps[0].seqs) != 1 || gaps[0].seqs[0] != 5 {
+		t.Errorf("unexpected gaps %v", gaps)
+	}
+
+	// the gap expires and is skipped
+	o.setGapPolicy(GapSkip, 0)
+	o.check(emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 1 || seqs[0] != 6 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+	o.add(sequenced(1, 5), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 0 {
+		t.Errorf("skipped message was delivered late: %v", seqs)
+	}
+
+	// a restarted origin starts over
+	o.add(sequenced(2, 1), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 1 || seqs[0] != 1 {
+		t.Errorf("unexpected delivery %v", seqs)
+	}
+}
+
+func TestOrdererReorderedStart(t *testing.T) {
+	msgChan := make(chan []byte, 16)
+	emit := emitTo(msgChan)
+	o := newOrderer()
+	o.setOrdering(OrderingFIFO)
+	o.setGapPolicy(GapWait, time.Second)
+
+	// the node joins after the origin started, and relaying swapped the
+	// first two messages it sees
+	o.add(sequenced(1, 5), emit)
+	o.add(sequenced(1, 4), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 0 {
+		t.Fatalf("delivered during the hold-down: %v", seqs)
+	}
+	o.check(emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 0 {
+		t.Fatalf("delivered during the hold-down: %v", seqs)
+	}
+	o.streams[sequenceKey("origin", 1, 0)].holdUntil = time.Now().Add(-time.Millisecond)
+	o.check(emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 2 || seqs[0] != 4 || seqs[1] != 5 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+	o.add(sequenced(1, 6), emit)
+	o.add(sequenced(1, 3), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 1 || seqs[0] != 6 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+}
+
+func TestOrdererEpochs(t *testing.T) {
+	msgChan := make(chan []byte, 16)
+	emit := emitTo(msgChan)
+	o := newOrderer()
+	o.setOrdering(OrderingFIFO)
+	o.setGapPolicy(GapWait, time.Second)
+
+	o.add(sequenced(1, 1), emit)
+	// a message claiming a later epoch does not cut off the running one
+	o.add(sequenced(math.MaxUint64, 7), emit)
+	o.add(sequenced(1, 3), emit)
+	o.add(sequenced(1, 2), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 3 || seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+
+	// an epoch that stopped is given up on once idle
+	o.add(sequenced(2, 1), emit)
+	o.add(sequenced(1, 5), emit)
+	receivedSeqs(msgChan)
+	old := o.streams[sequenceKey("origin", 1, 0)]
+	old.lastSeen = time.Now().Add(-streamIdleTimeout - time.Second)
+	o.check(emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 1 || seqs[0] != 5 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+	if _, ok := o.streams[sequenceKey("origin", 1, 0)]; ok {
+		t.Error("idle epoch kept")
+	}
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	if reason := node.checkSize(sequenced(math.MaxUint64, 1)); reason != ReasonFutureEpoch {
+		t.Errorf("message from a future epoch accepted: %q", reason)
+	}
+	if reason := node.checkSize(sequenced(uint64(time.Now().UnixNano()), 1)); reason != "" {
+		t.Errorf("message from the current epoch refused: %q", reason)
+	}
+}
+
+func TestOrdererPassed(t *testing.T) {
+	msgChan := make(chan []byte, 16)
+	emit := emitTo(msgChan)
+	o := newOrderer()
+	o.setOrdering(OrderingFIFO)
+	o.setGapPolicy(GapWait, time.Hour)
+
+	o.add(sequenced(1, 1), emit)
+	o.add(sequenced(1, 3), emit)
+	o.pass(sequenced(1, 2), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 3 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+	// the passed message was a forgery, the genuine one is delivered late
+	o.add(sequenced(1, 2), emit)
+	o.add(sequenced(1, 2), emit)
+	if seqs := receivedSeqs(msgChan); len(seqs) != 1 || seqs[0] != 2 {
+		t.Fatalf("unexpected delivery %v", seqs)
+	}
+}
+
+func TestRejectedInSequence(t *testing.T) {
+	for _, ordering := range []Ordering{OrderingFIFO, OrderingCausal} {
+		node := New(NewNodeId("127.0.0.1:0"), "test topic")
+		node.SetOrdering(ordering)
+		node.SetGapPolicy(GapWait, time.Hour)
+		node.AddValidator("test topic", func(ctx context.Context, from NodeId, data *GossipData) ValidationResult {
+			if string(data.Payload) == "bad" {
+				return ValidationReject
+			}
+			return ValidationAccept
+		})
+		sender := New(NewNodeId("127.0.0.1:9141"), "test topic")
+		sender.SetOrdering(ordering)
+		for _, payload := range []string{"one", "bad", "three"} {
+			data, _ := sender.newGossipData([]byte(payload))
+			node.SendData(context.Background(), data)
+		}
+		for _, expected := range []string{"one", "three"} {
+			select {
+			case msg := <-node.GetMsgChan():
+				if string(msg) != expected {
+					t.Errorf("delivered %s, expected %s", msg, expected)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s held up by a rejected message", expected)
+			}
+		}
+		node.Close()
+		sender.Close()
+	}
+}
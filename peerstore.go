@@ -0,0 +1,172 @@
+package gossip
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const peerStoreCap = 1024
+const peerStoreInterval = 30 * time.Second
+
+type PeerRecord struct {
+	NodeId      NodeId    `json:"nodeId"`
+	Successes   int       `json:"successes"`
+	Failures    int       `json:"failures"`
+	LastSeen    time.Time `json:"lastSeen"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastFailure time.Time `json:"lastFailure"`
+}
+
+func (record *PeerRecord) score() float64 {
+	return float64(record.Successes+1) / float64(record.Successes+record.Failures+2)
+}
+
+type PeerStore struct {
+	path    string
+	records map[NodeId]*PeerRecord
+	lock    *sync.Mutex
+}
+
+func NewPeerStore(path string) *PeerStore {
+	return &PeerStore{
+		path:    path,
+		records: make(map[NodeId]*PeerRecord),
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (ps *PeerStore) Load() error {
+	content, err := ioutil.ReadFile(ps.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("[gossip] Cannot read peer store %s: %s", ps.path, err.Error()))
+	}
+	records := make([]*PeerRecord, 0)
+	if err := json.Unmarshal(content, &records); err != nil {
+		return errors.New(fmt.Sprintf("[gossip] Cannot parse peer store %s: %s", ps.path, err.Error()))
+	}
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	for i := range records {
+		ps.records[records[i].NodeId] = records[i]
+	}
+	return nil
+}
+
+func (ps *PeerStore) Save() error {
+	ps.lock.Lock()
+	sorted := ps.sorted()
+	records := make([]PeerRecord, len(sorted))
+	for i := range sorted {
+		records[i] = *sorted[i]
+	}
+	ps.lock.Unlock()
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(ps.path, content); err != nil {
+		return errors.New(fmt.Sprintf("[gossip] Cannot write peer store %s: %s", ps.path, err.Error()))
+	}
+	return nil
+}
+
+func (ps *PeerStore) get(nodeId NodeId) *PeerRecord {
+	record, ok := ps.records[nodeId]
+	if !ok {
+		record = &PeerRecord{NodeId: nodeId}
+		ps.records[nodeId] = record
+	}
+	return record
+}
+
+func (ps *PeerStore) Seen(nodeIds []NodeId) {
+	current := time.Now()
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	for i := range nodeIds {
+		ps.get(nodeIds[i]).LastSeen = current
+	}
+	ps.truncate()
+}
+
+func (ps *PeerStore) RecordSuccess(nodeId NodeId) {
+	current := time.Now()
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	record := ps.get(nodeId)
+	record.Successes++
+	record.LastSeen = current
+	record.LastSuccess = current
+}
+
+func (ps *PeerStore) RecordFailure(nodeId NodeId) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	record := ps.get(nodeId)
+	record.Failures++
+	record.LastFailure = time.Now()
+}
+
+func (ps *PeerStore) Get(nodeId NodeId) (PeerRecord, bool) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if record, ok := ps.records[nodeId]; ok {
+		return *record, true
+	}
+	return PeerRecord{}, false
+}
+
+// Peers returns at most num stored peers, most reliable first.
+func (ps *PeerStore) Peers(num int) []NodeId {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	records := ps.sorted()
+	if num > len(records) {
+		num = len(records)
+	}
+	ret := make([]NodeId, num)
+	for i := 0; i < num; i++ {
+		ret[i] = records[i].NodeId
+	}
+	return ret
+}
+
+func (ps *PeerStore) Len() int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return len(ps.records)
+}
+
+func (ps *PeerStore) sorted() []*PeerRecord {
+	records := make([]*PeerRecord, 0, len(ps.records))
+	for _, record := range ps.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].score() != records[j].score() {
+			return records[i].score() > records[j].score()
+		}
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+	return records
+}
+
+func (ps *PeerStore) truncate() {
+	if len(ps.records) <= peerStoreCap {
+		return
+	}
+	records := ps.sorted()
+	for i := peerStoreCap; i < len(records); i++ {
+		delete(ps.records, records[i].NodeId)
+	}
+}
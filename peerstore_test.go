@@ -0,0 +1,42 @@
+package gossip
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeerStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gossip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peers.json")
+
+	store := NewPeerStore(path)
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	good := NewNodeId("127.0.0.1:9001")
+	bad := NewNodeId("127.0.0.1:9002")
+	store.Seen([]NodeId{good, bad})
+	store.RecordSuccess(good)
+	store.RecordFailure(bad)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewPeerStore(path)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	peers := loaded.Peers(10)
+	if len(peers) != 2 || peers[0] != good || peers[1] != bad {
+		t.Errorf("unexpected peers %v", peers)
+	}
+	if record, ok := loaded.Get(bad); !ok || record.Failures != 1 || record.LastFailure.IsZero() {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
@@ -0,0 +1,268 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const ackRouteTTL = 60 * time.Second
+const maxAckRoutes = 16384
+const ackTimeout = 5 * time.Second
+
+type PublishOptions struct {
+	// MinAcks makes Publish wait until that many nodes acknowledged the
+	// message. Acknowledgements are not authenticated: any node on the path
+	// can claim to speak for others, so they count delivery on a best effort
+	// basis, not a guarantee.
+	MinAcks int
+	// Coverage makes Publish wait until that fraction of the estimated
+	// network acknowledged the message.
+	Coverage float64
+	// Selector limits delivery to the matching nodes, see GossipTo.
+	Selector string
+}
+
+type PublishResult struct {
+	// Peers is the number of direct peers the message was sent to, and
+	// Accepted the number of them that took it.
+	Peers    int
+	Accepted int
+	// Acks is the number of nodes that delivered the message to their
+	// subscriber and acknowledged it, and Coverage the fraction of the
+	// estimated network that did, counting the publisher.
+	Acks     int
+	Coverage float64
+}
+
+// Publish gossips data like Gossip, but reports how far it got. It returns
+// an error if no direct peer took the message. Peers that have not answered
+// when ctx is done are not waited for. If opts asks for acknowledgements,
+// receivers acknowledge the message along the path it took, and Publish
+// waits until there are enough of them or ctx is done.
+func (node *Node) Publish(ctx context.Context, data []byte, opts PublishOptions) (*PublishResult, error) {
+	if _, err := ParseSelector(opts.Selector); err != nil {
+		return nil, err
+	}
+	gossipData, err := node.newGossipData(data)
+	if err != nil {
+		return nil, err
+	}
+	gossipData.Selector = opts.Selector
+	wantAcks := opts.MinAcks > 0 || opts.Coverage > 0
+	var acks *pendingAcks
+	if wantAcks {
+		gossipData.Ack = true
+		acks = node.acks.add(ref(gossipData).key())
+		defer node.acks.done(ref(gossipData).key())
+	}
+
+	result := &PublishResult{}
+	peers, results := node.publish(gossipData)
+	result.Peers = peers
+	for i := 0; i < peers && ctx.Err() == nil; i++ {
+		select {
+		case ok := <-results:
+			if ok {
+				result.Accepted++
+			}
+		case <-ctx.Done():
+		}
+	}
+	if result.Accepted == 0 {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, errors.New("[gossip] No peer accepted the message")
+	}
+	if !wantAcks {
+		return result, nil
+	}
+
+	for {
+		result.Acks = acks.count()
+		result.Coverage = float64(result.Acks+1) / float64(node.EstimatedSize())
+		if result.Coverage > 1 {
+			result.Coverage = 1
+		}
+		if result.Acks >= opts.MinAcks && result.Coverage >= opts.Coverage {
+			return result, nil
+		}
+		select {
+		case <-acks.notify:
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+}
+
+func (node *Node) Ack(ctx context.Context, ack *AckMsg) (*Empty, error) {
+	if ack.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if ack.Msg == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "[From %s] %s", node.NodeId().String(), "ack without message")
+	}
+	key := ack.Msg.key()
+	if ack.Msg.Origin == node.NodeId().String() {
+		node.acks.ack(key, ack.NodeId)
+		return &Empty{}, nil
+	}
+	if upstream, ok := node.ackRoutes.get(key); ok {
+		go node.sendAck(upstream, ack)
+	}
+	return &Empty{}, nil
+}
+
+// routeAcks remembers where a message that asks for acknowledgements came
+// from, so that acknowledgements can follow it back.
+func (node *Node) routeAcks(data *GossipData) {
+	if !data.Ack || data.NodeId == node.NodeId().String() {
+		return
+	}
+	node.ackRoutes.add(ref(data).key(), data.From())
+}
+
+// acknowledge sends the node's own acknowledgement of a message it
+// delivered to the subscriber. Messages that target other nodes, or are
+// still held back for ordering, are not acknowledged.
+func (node *Node) acknowledge(data *GossipData) {
+	if !data.Ack || data.NodeId == node.NodeId().String() {
+		return
+	}
+	upstream, ok := node.ackRoutes.get(ref(data).key())
+	if !ok {
+		return
+	}
+	go node.sendAck(upstream, &AckMsg{
+		Topic:  node.topic,
+		Msg:    ref(data),
+		NodeId: node.NodeId().String(),
+	})
+}
+
+func (node *Node) sendAck(nodeId NodeId, ack *AckMsg) {
+	conn, err := node.neighbors.GetConn(nodeId)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+	if _, err := NewGossipClient(conn).Ack(ctx, ack); err != nil && status.Code(err) != codes.Unimplemented {
+		log.Printf("[gossip] Cannot send ack to node %s: %s", nodeId.String(), err.Error())
+	}
+}
+
+type pendingAcks struct {
+	nodes  map[string]bool
+	notify chan struct{}
+	lock   *sync.Mutex
+}
+
+func (p *pendingAcks) count() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.nodes)
+}
+
+// ackTracker counts the distinct nodes that acknowledged the messages being
+// published.
+type ackTracker struct {
+	pending map[string]*pendingAcks
+	lock    *sync.Mutex
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{
+		pending: make(map[string]*pendingAcks),
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (t *ackTracker) add(key string) *pendingAcks {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	acks := &pendingAcks{
+		nodes:  make(map[string]bool),
+		notify: make(chan struct{}, 1),
+		lock:   &sync.Mutex{},
+	}
+	t.pending[key] = acks
+	return acks
+}
+
+func (t *ackTracker) done(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.pending, key)
+}
+
+func (t *ackTracker) ack(key string, nodeId string) {
+	t.lock.Lock()
+	acks, ok := t.pending[key]
+	t.lock.Unlock()
+	if !ok {
+		return
+	}
+	acks.lock.Lock()
+	acks.nodes[nodeId] = true
+	acks.lock.Unlock()
+	select {
+	case acks.notify <- struct{}{}:
+	default:
+	}
+}
+
+type ackRoute struct {
+	upstream NodeId
+	created  time.Time
+}
+
+// ackRoutes maps messages to the neighbor they were received from.
+type ackRoutes struct {
+	routes map[string]*ackRoute
+	lock   *sync.Mutex
+}
+
+func newAckRoutes() *ackRoutes {
+	return &ackRoutes{
+		routes: make(map[string]*ackRoute),
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (r *ackRoutes) add(key string, upstream NodeId) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.routes) >= maxAckRoutes {
+		r.truncate()
+		if len(r.routes) >= maxAckRoutes {
+			return
+		}
+	}
+	r.routes[key] = &ackRoute{upstream, time.Now()}
+}
+
+func (r *ackRoutes) get(key string) (NodeId, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	route, ok := r.routes[key]
+	if !ok || time.Since(route.created) > ackRouteTTL {
+		return "", false
+	}
+	return route.upstream, true
+}
+
+func (r *ackRoutes) truncate() {
+	current := time.Now()
+	for key, route := range r.routes {
+		if current.Sub(route.created) > ackRouteTTL {
+			delete(r.routes, key)
+		}
+	}
+}
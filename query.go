@@ -0,0 +1,285 @@
+package gossip
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const queryChannel = "gossip/query"
+const queryResponseChannel = "gossip/query-response"
+const defaultQueryTimeout = 10 * time.Second
+
+// queries without MaxResponses buffer this many responses and acks, and
+// make further ones wait for the caller until the query is over
+const queryResponseBuffer = 64
+
+// QueryHandler answers a query. The node does not respond if it returns an
+// error.
+type QueryHandler func(ctx context.Context, from NodeId, payload []byte) ([]byte, error)
+
+type QueryOptions struct {
+	// Filter restricts the query to nodes whose metadata has all of its
+	// key/value pairs.
+	Filter map[string]string
+	// MaxResponses stops collecting after that many responses. Zero means no
+	// limit.
+	MaxResponses int
+	// RelayAck makes every node that receives the query acknowledge it,
+	// whether it responds or not.
+	RelayAck bool
+	// Timeout bounds how long responses are collected, unless ctx ends
+	// earlier. It defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+type QueryResponse struct {
+	From    NodeId
+	Payload []byte
+}
+
+// QueryResult streams the responses and acknowledgements of a query. Both
+// channels are closed when the query is over.
+type QueryResult struct {
+	Responses <-chan QueryResponse
+	Acks      <-chan NodeId
+}
+
+// HandleQuery registers the handler for queries called name. A nil handler
+// removes it.
+func (node *Node) HandleQuery(name string, handler QueryHandler) {
+	node.queries.lock.Lock()
+	defer node.queries.lock.Unlock()
+	if handler == nil {
+		delete(node.queries.handlers, name)
+		return
+	}
+	node.queries.handlers[name] = handler
+}
+
+// Query gossips a query to the nodes that match opts.Filter, including the
+// node itself. The nodes that have a handler for name send their responses
+// straight back.
+func (node *Node) Query(ctx context.Context, name string, payload []byte, opts QueryOptions) (*QueryResult, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	query := &QueryMsg{
+		Name:     name,
+		Payload:  payload,
+		Filter:   opts.Filter,
+		RelayAck: opts.RelayAck,
+	}
+	pending := node.queries.add(opts.MaxResponses)
+	query.Id = pending.id
+	data, err := proto.Marshal(query)
+	if err != nil {
+		node.queries.done(pending.id)
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-pending.finished:
+		}
+		cancel()
+		node.queries.done(pending.id)
+	}()
+	if err := node.gossipChannel(queryChannel, data); err != nil {
+		cancel()
+		return nil, err
+	}
+	return &QueryResult{
+		Responses: pending.responses,
+		Acks:      pending.acks,
+	}, nil
+}
+
+func (node *Node) registerQueries() {
+	node.handleChannel(queryChannel, node.receiveQuery)
+	node.HandleDirect(queryResponseChannel, func(ctx context.Context, from NodeId, payload []byte) ([]byte, error) {
+		res := &QueryRes{}
+		if err := proto.Unmarshal(payload, res); err != nil {
+			return nil, err
+		}
+		res.NodeId = from.String()
+		node.queries.respond(res)
+		return nil, nil
+	})
+}
+
+func (node *Node) receiveQuery(data *GossipData) {
+	query := &QueryMsg{}
+	if err := proto.Unmarshal(data.Payload, query); err != nil {
+		log.Printf("[gossip] Malformed query from %s: %s", data.NodeId, err.Error())
+		return
+	}
+	origin := NewNodeId(data.NodeId)
+	if query.RelayAck {
+		go node.respondQuery(origin, &QueryRes{Id: query.Id, Ack: true})
+	}
+	if !matchEntries(node.Metadata(), query.Filter) {
+		return
+	}
+	node.queries.lock.Lock()
+	handler, ok := node.queries.handlers[query.Name]
+	node.queries.lock.Unlock()
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), directHandlerTimeout)
+		defer cancel()
+		payload, err := handler(ctx, origin, query.Payload)
+		if err != nil {
+			return
+		}
+		node.respondQuery(origin, &QueryRes{Id: query.Id, Payload: payload})
+	}()
+}
+
+func (node *Node) respondQuery(origin NodeId, res *QueryRes) {
+	if origin == node.NodeId() {
+		res.NodeId = origin.String()
+		node.queries.respond(res)
+		return
+	}
+	payload, err := proto.Marshal(res)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), directTimeout)
+	defer cancel()
+	if err := node.sendDirect(ctx, origin, queryResponseChannel, payload, 0); err != nil {
+		log.Printf("[gossip] Cannot respond to query of %s: %s", origin.String(), err.Error())
+	}
+}
+
+type pendingQuery struct {
+	id        uint64
+	responses chan QueryResponse
+	acks      chan NodeId
+	responded map[string]bool
+	acked     map[string]bool
+	max       int
+	finished  chan struct{}
+	// over is closed when the query is over, which releases the responses
+	// still waiting for the caller, counted by sending.
+	over    chan struct{}
+	sending *sync.WaitGroup
+}
+
+// queries holds the query handlers of the node and the queries it is
+// collecting responses for.
+type queries struct {
+	handlers map[string]QueryHandler
+	pending  map[uint64]*pendingQuery
+	lock     *sync.Mutex
+}
+
+func newQueries() *queries {
+	return &queries{
+		handlers: make(map[string]QueryHandler),
+		pending:  make(map[uint64]*pendingQuery),
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (q *queries) add(max int) *pendingQuery {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	id := rand.Uint64()
+	for _, ok := q.pending[id]; ok; _, ok = q.pending[id] {
+		id = rand.Uint64()
+	}
+	// with a limit, every response fits and the query never ends on
+	// responses the caller has not been given
+	buffer := queryResponseBuffer
+	if max > 0 {
+		buffer = max
+	}
+	pending := &pendingQuery{
+		id:        id,
+		responses: make(chan QueryResponse, buffer),
+		acks:      make(chan NodeId, queryResponseBuffer),
+		responded: make(map[string]bool),
+		acked:     make(map[string]bool),
+		max:       max,
+		finished:  make(chan struct{}),
+		over:      make(chan struct{}),
+		sending:   &sync.WaitGroup{},
+	}
+	q.pending[id] = pending
+	return pending
+}
+
+// done ends a query and closes its channels.
+func (q *queries) done(id uint64) {
+	q.lock.Lock()
+	pending, ok := q.pending[id]
+	delete(q.pending, id)
+	q.lock.Unlock()
+	if !ok {
+		return
+	}
+	close(pending.over)
+	pending.sending.Wait()
+	close(pending.responses)
+	close(pending.acks)
+}
+
+// respond passes a response or acknowledgement to its query, once per node.
+// It waits for the caller to take them until the query is over.
+func (q *queries) respond(res *QueryRes) {
+	q.lock.Lock()
+	pending, ok := q.pending[res.Id]
+	if !ok {
+		q.lock.Unlock()
+		return
+	}
+	if res.Ack {
+		if pending.acked[res.NodeId] {
+			q.lock.Unlock()
+			return
+		}
+		pending.acked[res.NodeId] = true
+		pending.sending.Add(1)
+		q.lock.Unlock()
+		defer pending.sending.Done()
+		select {
+		case pending.acks <- NewNodeId(res.NodeId):
+		case <-pending.over:
+		}
+		return
+	}
+	if pending.responded[res.NodeId] || pending.max > 0 && len(pending.responded) >= pending.max {
+		q.lock.Unlock()
+		return
+	}
+	pending.responded[res.NodeId] = true
+	pending.sending.Add(1)
+	// responses within the limit fit into the buffer, so the query is only
+	// finished once they are all in it
+	select {
+	case pending.responses <- QueryResponse{NewNodeId(res.NodeId), res.Payload}:
+		pending.sending.Done()
+		if pending.max > 0 && len(pending.responded) >= pending.max {
+			close(pending.finished)
+		}
+		q.lock.Unlock()
+		return
+	default:
+	}
+	q.lock.Unlock()
+	defer pending.sending.Done()
+	select {
+	case pending.responses <- QueryResponse{NewNodeId(res.NodeId), res.Payload}:
+	case <-pending.over:
+	}
+}
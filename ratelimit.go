@@ -0,0 +1,256 @@
+package gossip
+
+import (
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const rateLimiterIdle = 10 * time.Minute
+
+const ReasonRateLimited = "rate limited"
+
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   *sync.Mutex
+}
+
+// NewTokenBucket allows rate per second on average and up to burst at once.
+func NewTokenBucket(rate float64, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (tb *TokenBucket) Allow(n float64) bool {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	if !tb.has(n) {
+		return false
+	}
+	tb.tokens -= n
+	return true
+}
+
+// has refills the bucket and reports whether it holds n tokens. The caller
+// holds the lock.
+func (tb *TokenBucket) has(n float64) bool {
+	current := time.Now()
+	tb.tokens += current.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = current
+	return tb.tokens >= n
+}
+
+type RateLimitAction int
+
+const (
+	// RateLimitDrop drops the message and acks it as rejected.
+	RateLimitDrop RateLimitAction = iota
+	// RateLimitResourceExhausted fails the call with ResourceExhausted.
+	RateLimitResourceExhausted
+	// RateLimitPenalize drops the message and lowers the peer's score.
+	RateLimitPenalize
+)
+
+// RateLimits are per second, zero means unlimited. Peer limits apply to the
+// connection a message is relayed over, origin limits to the node that
+// published it.
+type RateLimits struct {
+	PeerMessages   float64
+	PeerBytes      float64
+	OriginMessages float64
+	OriginBytes    float64
+	GlobalMessages float64
+	GlobalBytes    float64
+	// Burst is how many seconds worth of each limit may arrive at once. A
+	// message larger than that passes only when its byte buckets are full.
+	Burst  float64
+	Action RateLimitAction
+}
+
+type RateLimitCounters struct {
+	Peer   uint64
+	Origin uint64
+	Global uint64
+}
+
+type rateLimiter struct {
+	limit    float64
+	buckets  map[NodeId]*TokenBucket
+	lastUsed map[NodeId]time.Time
+}
+
+func newRateLimiter(limit float64) *rateLimiter {
+	return &rateLimiter{
+		limit:    limit,
+		buckets:  make(map[NodeId]*TokenBucket),
+		lastUsed: make(map[NodeId]time.Time),
+	}
+}
+
+// charge returns the bucket of nodeId and how many tokens a message of n
+// takes from it, or nil if there is no limit. Messages beyond the burst take
+// a full bucket, so that they can pass at the limit rather than never.
+func (rl *rateLimiter) charge(nodeId NodeId, n float64, burst float64) (*TokenBucket, float64) {
+	if rl.limit <= 0 {
+		return nil, 0
+	}
+	bucket, ok := rl.buckets[nodeId]
+	if !ok {
+		bucket = NewTokenBucket(rl.limit, rl.limit*burst)
+		rl.buckets[nodeId] = bucket
+	}
+	rl.lastUsed[nodeId] = time.Now()
+	if n > bucket.burst {
+		n = bucket.burst
+	}
+	return bucket, n
+}
+
+func (rl *rateLimiter) truncate() {
+	current := time.Now()
+	for nodeId, lastUsed := range rl.lastUsed {
+		if current.Sub(lastUsed) > rateLimiterIdle {
+			delete(rl.buckets, nodeId)
+			delete(rl.lastUsed, nodeId)
+		}
+	}
+}
+
+type RateLimiter struct {
+	limits         RateLimits
+	peerMessages   *rateLimiter
+	peerBytes      *rateLimiter
+	originMessages *rateLimiter
+	originBytes    *rateLimiter
+	globalMessages *rateLimiter
+	globalBytes    *rateLimiter
+	counters       RateLimitCounters
+	lastTruncate   time.Time
+	lock           *sync.Mutex
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+	return &RateLimiter{
+		limits:         limits,
+		peerMessages:   newRateLimiter(limits.PeerMessages),
+		peerBytes:      newRateLimiter(limits.PeerBytes),
+		originMessages: newRateLimiter(limits.OriginMessages),
+		originBytes:    newRateLimiter(limits.OriginBytes),
+		globalMessages: newRateLimiter(limits.GlobalMessages),
+		globalBytes:    newRateLimiter(limits.GlobalBytes),
+		lastTruncate:   time.Now(),
+		lock:           &sync.Mutex{},
+	}
+}
+
+// Allow reports whether a message of size bytes relayed by peer and
+// published by origin is within all limits.
+func (rl *RateLimiter) Allow(peer NodeId, origin NodeId, size int) bool {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	if time.Since(rl.lastTruncate) > rateLimiterIdle {
+		for _, limiter := range []*rateLimiter{rl.peerMessages, rl.peerBytes, rl.originMessages, rl.originBytes} {
+			limiter.truncate()
+		}
+		rl.lastTruncate = time.Now()
+	}
+
+	// every bucket is checked before any is charged, so that messages over
+	// one limit do not use up the others, and a flooding peer does not use
+	// up the global limit for everyone else
+	burst := rl.limits.Burst
+	checks := []struct {
+		limiter *rateLimiter
+		nodeId  NodeId
+		n       float64
+		counter *uint64
+	}{
+		{rl.peerMessages, peer, 1, &rl.counters.Peer},
+		{rl.peerBytes, peer, float64(size), &rl.counters.Peer},
+		{rl.originMessages, origin, 1, &rl.counters.Origin},
+		{rl.originBytes, origin, float64(size), &rl.counters.Origin},
+		{rl.globalMessages, "", 1, &rl.counters.Global},
+		{rl.globalBytes, "", float64(size), &rl.counters.Global},
+	}
+	buckets := make([]*TokenBucket, len(checks))
+	amounts := make([]float64, len(checks))
+	for i, check := range checks {
+		buckets[i], amounts[i] = check.limiter.charge(check.nodeId, check.n, burst)
+		if buckets[i] == nil {
+			continue
+		}
+		buckets[i].lock.Lock()
+		ok := buckets[i].has(amounts[i])
+		buckets[i].lock.Unlock()
+		if !ok {
+			*check.counter++
+			return false
+		}
+	}
+	for i, bucket := range buckets {
+		if bucket != nil {
+			bucket.lock.Lock()
+			bucket.tokens -= amounts[i]
+			bucket.lock.Unlock()
+		}
+	}
+	return true
+}
+
+func (rl *RateLimiter) Action() RateLimitAction {
+	return rl.limits.Action
+}
+
+func (rl *RateLimiter) Counters() RateLimitCounters {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	return rl.counters
+}
+
+// SetRateLimits limits the messages the node accepts from its neighbors.
+func (node *Node) SetRateLimits(limits RateLimits) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.rateLimiter = NewRateLimiter(limits)
+}
+
+// RateLimitCounters returns how many messages exceeded each kind of limit.
+func (node *Node) RateLimitCounters() RateLimitCounters {
+	rateLimiter := node.getRateLimiter()
+	if rateLimiter == nil {
+		return RateLimitCounters{}
+	}
+	return rateLimiter.Counters()
+}
+
+func (node *Node) getRateLimiter() *RateLimiter {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.rateLimiter
+}
+
+func (node *Node) rateLimited(action RateLimitAction, from NodeId) (*SendDataRes, error) {
+	switch action {
+	case RateLimitResourceExhausted:
+		return nil, status.Errorf(codes.ResourceExhausted, "[From %s] %s", node.NodeId().String(), ReasonRateLimited)
+	case RateLimitPenalize:
+		node.score(from, ScoreRateLimited)
+	}
+	return &SendDataRes{Status: SendDataRes_REJECTED, Reason: ReasonRateLimited}, nil
+}
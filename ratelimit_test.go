@@ -0,0 +1,102 @@
+package gossip
+
+import (
+	"context"
+	"testing"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitPeerIsolation(t *testing.T) {
+	rl := NewRateLimiter(RateLimits{PeerMessages: 5, GlobalMessages: 20})
+	flooder, honest, origin := NewNodeId("flooder"), NewNodeId("honest"), NewNodeId("origin")
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if rl.Allow(flooder, origin, 10) {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Fatalf("flooding peer got %d messages through, expected 5", allowed)
+	}
+	for i := 0; i < 5; i++ {
+		if !rl.Allow(honest, origin, 10) {
+			t.Fatalf("honest peer throttled after %d messages", i)
+		}
+	}
+	if counters := rl.Counters(); counters != (RateLimitCounters{Peer: 95}) {
+		t.Fatalf("unexpected counters %+v", counters)
+	}
+	for i := 0; i < 20; i++ {
+		rl.Allow(NewNodeId(string(rune('a'+i))), origin, 10)
+	}
+	if counters := rl.Counters(); counters.Global != 10 {
+		t.Fatalf("unexpected counters %+v", counters)
+	}
+}
+
+func TestRateLimitCharging(t *testing.T) {
+	rl := NewRateLimiter(RateLimits{PeerMessages: 10, PeerBytes: 100, OriginMessages: 10})
+	peer, origin := NewNodeId("peer"), NewNodeId("origin")
+	// larger than the byte burst, so it takes the whole bucket
+	if !rl.Allow(peer, origin, 1000) {
+		t.Fatal("message larger than the burst can never pass")
+	}
+	if rl.Allow(peer, origin, 50) {
+		t.Fatal("message over the byte limit passed")
+	}
+	// the byte limit rejected it, so the message limits were not charged
+	if tokens := rl.peerMessages.buckets[peer].tokens; tokens > 9.5 || tokens < 8.5 {
+		t.Errorf("peer message bucket has %f tokens, expected 9", tokens)
+	}
+	if tokens := rl.originMessages.buckets[origin].tokens; tokens > 9.5 || tokens < 8.5 {
+		t.Errorf("origin message bucket has %f tokens, expected 9", tokens)
+	}
+	if counters := rl.Counters(); counters != (RateLimitCounters{Peer: 1}) {
+		t.Fatalf("unexpected counters %+v", counters)
+	}
+
+	rl = NewRateLimiter(RateLimits{OriginMessages: 1})
+	if !rl.Allow(peer, origin, 1) || rl.Allow(NewNodeId("other"), origin, 1) {
+		t.Fatal("origin limit not applied across peers")
+	}
+	if counters := rl.Counters(); counters != (RateLimitCounters{Origin: 1}) {
+		t.Fatalf("unexpected counters %+v", counters)
+	}
+}
+
+func TestRateLimitActions(t *testing.T) {
+	sender := New(NewNodeId("127.0.0.1:9131"), "test topic")
+	for _, action := range []RateLimitAction{RateLimitDrop, RateLimitResourceExhausted, RateLimitPenalize} {
+		node := New(NewNodeId("127.0.0.1:0"), "test topic")
+		node.SetRateLimits(RateLimits{PeerMessages: 1, Action: action})
+		first, _ := sender.newGossipData([]byte("first"))
+		second, _ := sender.newGossipData([]byte("second"))
+		if res, err := node.SendData(context.Background(), first); err != nil || res.Status != SendDataRes_NEW {
+			t.Fatalf("first message not taken: %v, %v", res, err)
+		}
+		res, err := node.SendData(context.Background(), second)
+		switch action {
+		case RateLimitResourceExhausted:
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("expected ResourceExhausted, got %v, %v", res, err)
+			}
+		default:
+			if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonRateLimited {
+				t.Errorf("expected rate limited, got %v, %v", res, err)
+			}
+		}
+		score := node.PeerScore(sender.NodeId())
+		if action == RateLimitPenalize && score >= 1 {
+			t.Errorf("rate limited peer not penalized, score %f", score)
+		}
+		if action != RateLimitPenalize && score != 1 {
+			t.Errorf("peer score %f, expected only the first delivery", score)
+		}
+		if counters := node.RateLimitCounters(); counters != (RateLimitCounters{Peer: 1}) {
+			t.Errorf("unexpected counters %+v", counters)
+		}
+		node.Close()
+	}
+}
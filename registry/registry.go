@@ -0,0 +1,454 @@
+// Package registry discovers services over a gossip node. Nodes register
+// the services they run, registrations spread by gossip and expire unless
+// their node keeps refreshing them, and every node answers lookups from
+// what it heard.
+package registry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+// registrations are gossiped and synced under this name
+const channel = "registry/services"
+const defaultTTL = 30 * time.Second
+const maxTTL = time.Hour
+
+// registrations refreshed further ahead of the local clock are refused
+const maxClockSkew = time.Minute
+const expireInterval = time.Second
+
+type Health int
+
+const (
+	HealthPassing Health = iota
+	HealthWarning
+	HealthCritical
+)
+
+type Service struct {
+	Name string
+	// Id tells apart instances of a service on the same node. It defaults
+	// to Name.
+	Id      string
+	Address string
+	Port    int
+	Tags    []string
+	Health  Health
+	// TTL is how long the registration lasts without a refresh. It defaults
+	// to 30 seconds, and the node refreshes it every third of it. It may be
+	// at most an hour.
+	TTL time.Duration
+}
+
+type Instance struct {
+	Service
+	NodeId gossip.NodeId
+	// Expires is when the registration expires unless refreshed.
+	Expires time.Time
+}
+
+// WatchFunc is called with the healthy instances of a service whenever
+// they change.
+type WatchFunc func(instances []Instance)
+
+type watcher struct {
+	service string
+	fn      WatchFunc
+}
+
+// Registry holds the services registered on all nodes. Expiry relies on
+// the clocks of nodes being roughly in sync, since registrations expire a
+// TTL after their node refreshed them.
+type Registry struct {
+	node          *gossip.Node
+	registrations map[string]*Registration
+	own           map[string]*Registration
+	watchers      map[int]*watcher
+	nextWatcher   int
+	notified      map[string][]Instance
+	closed        chan struct{}
+	once          *sync.Once
+	lock          *sync.Mutex
+}
+
+// New starts a registry on node. Registrations are gossiped as they
+// change, and synced with neighbors so that new nodes learn the ones of
+// their neighbors without waiting for a refresh.
+func New(node *gossip.Node) (*Registry, error) {
+	r := &Registry{
+		node:          node,
+		registrations: make(map[string]*Registration),
+		own:           make(map[string]*Registration),
+		watchers:      make(map[int]*watcher),
+		notified:      make(map[string][]Instance),
+		closed:        make(chan struct{}),
+		once:          &sync.Once{},
+		lock:          &sync.Mutex{},
+	}
+	if err := node.HandleChannel(channel, r.receive); err != nil {
+		return nil, err
+	}
+	node.AddSyncable(channel, r)
+	go r.maintain()
+	return r, nil
+}
+
+// Register registers service on the node, or updates it.
+func (r *Registry) Register(service Service) error {
+	if service.Name == "" {
+		return errors.New("[registry] Service without a name")
+	}
+	if service.Id == "" {
+		service.Id = service.Name
+	}
+	if service.TTL <= 0 {
+		service.TTL = defaultTTL
+	}
+	if service.TTL > maxTTL {
+		return errors.New(fmt.Sprintf("[registry] TTL %s is longer than %s", service.TTL.String(), maxTTL.String()))
+	}
+	reg := &Registration{
+		NodeId:  r.node.NodeId().String(),
+		Id:      service.Id,
+		Service: service.Name,
+		Address: service.Address,
+		Port:    uint32(service.Port),
+		Tags:    append([]string(nil), service.Tags...),
+		Health:  int32(service.Health),
+		Ttl:     int64(service.TTL),
+	}
+	r.lock.Lock()
+	r.own[reg.Id] = reg
+	r.lock.Unlock()
+	return r.announce(reg)
+}
+
+// SetHealth updates the health of the service registered with id.
+func (r *Registry) SetHealth(id string, health Health) error {
+	r.lock.Lock()
+	reg, ok := r.own[id]
+	if ok {
+		reg = proto.Clone(reg).(*Registration)
+		reg.Health = int32(health)
+		r.own[id] = reg
+	}
+	r.lock.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("[registry] No service registered with id %q", id))
+	}
+	return r.announce(reg)
+}
+
+// Deregister removes the service registered with id.
+func (r *Registry) Deregister(id string) error {
+	r.lock.Lock()
+	reg, ok := r.own[id]
+	delete(r.own, id)
+	r.lock.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("[registry] No service registered with id %q", id))
+	}
+	reg = proto.Clone(reg).(*Registration)
+	reg.Deregistered = true
+	return r.announce(reg)
+}
+
+// Lookup returns the healthy instances of the service called name that have
+// all of tags, from what the node heard.
+func (r *Registry) Lookup(name string, tags ...string) []Instance {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lookup(name, tags, time.Now())
+}
+
+// Watch calls fn with the healthy instances of the service called name
+// whenever they change, until the returned function is called.
+func (r *Registry) Watch(name string, fn WatchFunc) func() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	id := r.nextWatcher
+	r.nextWatcher++
+	r.watchers[id] = &watcher{name, fn}
+	if _, ok := r.notified[name]; !ok {
+		r.notified[name] = r.lookup(name, nil, time.Now())
+	}
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.watchers, id)
+	}
+}
+
+// Close deregisters the services of the node and stops the registry. The
+// node keeps running.
+func (r *Registry) Close() {
+	r.once.Do(func() {
+		r.lock.Lock()
+		ids := make([]string, 0, len(r.own))
+		for id := range r.own {
+			ids = append(ids, id)
+		}
+		r.lock.Unlock()
+		for _, id := range ids {
+			r.Deregister(id)
+		}
+		close(r.closed)
+		r.node.HandleChannel(channel, nil)
+		r.node.AddSyncable(channel, nil)
+	})
+}
+
+// lookup returns the healthy instances of name. The caller holds the lock.
+func (r *Registry) lookup(name string, tags []string, now time.Time) []Instance {
+	var instances []Instance
+	for _, reg := range r.registrations {
+		if reg.Service != name || reg.Deregistered || Health(reg.Health) != HealthPassing || !expires(reg).After(now) || !hasTags(reg, tags) {
+			continue
+		}
+		instances = append(instances, instance(reg))
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].NodeId != instances[j].NodeId {
+			return instances[i].NodeId < instances[j].NodeId
+		}
+		return instances[i].Id < instances[j].Id
+	})
+	return instances
+}
+
+func instance(reg *Registration) Instance {
+	return Instance{
+		Service: Service{
+			Name:    reg.Service,
+			Id:      reg.Id,
+			Address: reg.Address,
+			Port:    int(reg.Port),
+			Tags:    append([]string(nil), reg.Tags...),
+			Health:  Health(reg.Health),
+			TTL:     time.Duration(reg.Ttl),
+		},
+		NodeId:  gossip.NewNodeId(reg.NodeId),
+		Expires: expires(reg),
+	}
+}
+
+func hasTags(reg *Registration, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for i := range reg.Tags {
+			if reg.Tags[i] == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func key(reg *Registration) string {
+	return reg.NodeId + "/" + reg.Id
+}
+
+func expires(reg *Registration) time.Time {
+	return time.Unix(0, reg.Version+reg.Ttl)
+}
+
+// announce refreshes reg and gossips it.
+func (r *Registry) announce(reg *Registration) error {
+	reg = proto.Clone(reg).(*Registration)
+	reg.Version = time.Now().UnixNano()
+	r.apply(reg)
+	data, err := proto.Marshal(&Announcement{Registrations: []*Registration{reg}})
+	if err != nil {
+		return err
+	}
+	return r.node.GossipChannel(channel, data)
+}
+
+func (r *Registry) receive(from gossip.NodeId, payload []byte) {
+	announcement := &Announcement{}
+	if err := proto.Unmarshal(payload, announcement); err != nil {
+		log.Printf("[registry] Malformed announcement from %s: %s", from.String(), err.Error())
+		return
+	}
+	for _, reg := range announcement.Registrations {
+		// nodes only announce their own services
+		if reg.NodeId != from.String() {
+			continue
+		}
+		r.apply(reg)
+	}
+}
+
+// apply keeps reg if it is newer than what the node has, and notifies the
+// watchers of its service if its instances changed. Registrations from the
+// future or with a TTL no node registers with are dropped, so that none can
+// outlive its node.
+func (r *Registry) apply(reg *Registration) {
+	now := time.Now()
+	if reg.Ttl <= 0 || reg.Ttl > int64(maxTTL) || reg.Version > now.Add(maxClockSkew).UnixNano() {
+		return
+	}
+	if !expires(reg).After(now) {
+		return
+	}
+	r.lock.Lock()
+	current, ok := r.registrations[key(reg)]
+	if ok && current.Version >= reg.Version {
+		r.lock.Unlock()
+		return
+	}
+	r.registrations[key(reg)] = reg
+	r.lock.Unlock()
+	r.notify(reg.Service)
+	if ok && current.Service != reg.Service {
+		r.notify(current.Service)
+	}
+}
+
+// notify calls the watchers of service if its instances changed since they
+// were last called.
+func (r *Registry) notify(service string) {
+	r.lock.Lock()
+	var fns []WatchFunc
+	for _, w := range r.watchers {
+		if w.service == service {
+			fns = append(fns, w.fn)
+		}
+	}
+	if len(fns) == 0 {
+		delete(r.notified, service)
+		r.lock.Unlock()
+		return
+	}
+	instances := r.lookup(service, nil, time.Now())
+	if sameInstances(r.notified[service], instances) {
+		r.lock.Unlock()
+		return
+	}
+	r.notified[service] = instances
+	r.lock.Unlock()
+	for _, fn := range fns {
+		fn(instances)
+	}
+}
+
+func sameInstances(a, b []Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].NodeId != b[i].NodeId || a[i].Id != b[i].Id || a[i].Address != b[i].Address || a[i].Port != b[i].Port || !reflect.DeepEqual(a[i].Tags, b[i].Tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// maintain refreshes the services of the node, and drops expired
+// registrations, until the registry is closed.
+func (r *Registry) maintain() {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.closed:
+			return
+		case <-ticker.C:
+		}
+		r.refresh()
+		r.expire()
+	}
+}
+
+func (r *Registry) refresh() {
+	now := time.Now()
+	r.lock.Lock()
+	var due []*Registration
+	for _, reg := range r.own {
+		if current, ok := r.registrations[key(reg)]; !ok || now.Sub(time.Unix(0, current.Version)) >= time.Duration(reg.Ttl)/3 {
+			due = append(due, reg)
+		}
+	}
+	r.lock.Unlock()
+	for _, reg := range due {
+		if err := r.announce(reg); err != nil {
+			log.Printf("[registry] Cannot refresh service %s: %s", reg.Id, err.Error())
+		}
+	}
+}
+
+func (r *Registry) expire() {
+	now := time.Now()
+	r.lock.Lock()
+	expired := make(map[string]bool)
+	for k, reg := range r.registrations {
+		if !expires(reg).After(now) {
+			expired[reg.Service] = true
+			delete(r.registrations, k)
+		}
+	}
+	r.lock.Unlock()
+	for service := range expired {
+		r.notify(service)
+	}
+}
+
+// Digests returns the version of every registration, so that syncing
+// neighbors exchange the ones that are newer on either side.
+func (r *Registry) Digests() map[string][]byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	digests := make(map[string][]byte, len(r.registrations))
+	for k, reg := range r.registrations {
+		digest := make([]byte, 8)
+		binary.BigEndian.PutUint64(digest, uint64(reg.Version))
+		digests[k] = digest
+	}
+	return digests
+}
+
+func (r *Registry) Value(key string) ([]byte, bool) {
+	r.lock.Lock()
+	reg, ok := r.registrations[key]
+	r.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+	data, err := proto.Marshal(reg)
+	return data, err == nil
+}
+
+func (r *Registry) Merge(k string, value []byte) error {
+	return errors.New("[registry] Registrations need a sender")
+}
+
+// MergeFrom takes a registration a peer synced. Like announcements, only
+// the node a registration belongs to can vouch for it.
+func (r *Registry) MergeFrom(from gossip.NodeId, k string, value []byte) error {
+	reg := &Registration{}
+	if err := proto.Unmarshal(value, reg); err != nil {
+		return err
+	}
+	if key(reg) != k {
+		return errors.New(fmt.Sprintf("[registry] Registration %s sent as %s", key(reg), k))
+	}
+	if reg.NodeId != from.String() {
+		return errors.New(fmt.Sprintf("[registry] Registration %s sent by %s", k, from.String()))
+	}
+	r.apply(reg)
+	return nil
+}
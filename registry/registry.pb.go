@@ -0,0 +1,198 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: registry.proto
+
+package registry
+
+import (
+	fmt "fmt"
+	math "math"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Registration struct {
+	NodeId               string   `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Id                   string   `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Service              string   `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	Address              string   `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Port                 uint32   `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`
+	Tags                 []string `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Health               int32    `protobuf:"varint,7,opt,name=health,proto3" json:"health,omitempty"`
+	Version              int64    `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	Ttl                  int64    `protobuf:"varint,9,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Deregistered         bool     `protobuf:"varint,10,opt,name=deregistered,proto3" json:"deregistered,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Registration) Reset()         { *m = Registration{} }
+func (m *Registration) String() string { return proto.CompactTextString(m) }
+func (*Registration) ProtoMessage()    {}
+func (*Registration) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{0}
+}
+
+func (m *Registration) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Registration.Unmarshal(m, b)
+}
+func (m *Registration) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Registration.Marshal(b, m, deterministic)
+}
+func (m *Registration) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Registration.Merge(m, src)
+}
+func (m *Registration) XXX_Size() int {
+	return xxx_messageInfo_Registration.Size(m)
+}
+func (m *Registration) XXX_DiscardUnknown() {
+	xxx_messageInfo_Registration.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Registration proto.InternalMessageInfo
+
+func (m *Registration) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *Registration) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Registration) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *Registration) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Registration) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *Registration) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *Registration) GetHealth() int32 {
+	if m != nil {
+		return m.Health
+	}
+	return 0
+}
+
+func (m *Registration) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Registration) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *Registration) GetDeregistered() bool {
+	if m != nil {
+		return m.Deregistered
+	}
+	return false
+}
+
+type Announcement struct {
+	Registrations        []*Registration `protobuf:"bytes,1,rep,name=registrations,proto3" json:"registrations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *Announcement) Reset()         { *m = Announcement{} }
+func (m *Announcement) String() string { return proto.CompactTextString(m) }
+func (*Announcement) ProtoMessage()    {}
+func (*Announcement) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41af05d40a615591, []int{1}
+}
+
+func (m *Announcement) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Announcement.Unmarshal(m, b)
+}
+func (m *Announcement) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Announcement.Marshal(b, m, deterministic)
+}
+func (m *Announcement) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Announcement.Merge(m, src)
+}
+func (m *Announcement) XXX_Size() int {
+	return xxx_messageInfo_Announcement.Size(m)
+}
+func (m *Announcement) XXX_DiscardUnknown() {
+	xxx_messageInfo_Announcement.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Announcement proto.InternalMessageInfo
+
+func (m *Announcement) GetRegistrations() []*Registration {
+	if m != nil {
+		return m.Registrations
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Registration)(nil), "registry.Registration")
+	proto.RegisterType((*Announcement)(nil), "registry.Announcement")
+}
+
+func init() { proto.RegisterFile("registry.proto", fileDescriptor_41af05d40a615591) }
+
+var fileDescriptor_41af05d40a615591 = []byte{
+	// 240 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x90, 0xb1, 0x6e, 0xc3, 0x20,
+	0x14, 0x45, 0x85, 0x49, 0x1c, 0xfb, 0xd5, 0x89, 0x2a, 0x86, 0xe8, 0x8d, 0xc8, 0x13, 0x53, 0x86,
+	0x76, 0xed, 0xd2, 0xb1, 0x52, 0x27, 0xfe, 0xc0, 0x0d, 0x4f, 0x09, 0x52, 0x0a, 0x11, 0xd0, 0x48,
+	0xfd, 0xf5, 0x4e, 0x15, 0xd8, 0x8e, 0xe2, 0xed, 0x9e, 0x73, 0x11, 0x82, 0x0b, 0xbb, 0x40, 0x27,
+	0x1b, 0x53, 0xf8, 0x3d, 0x5c, 0x83, 0x4f, 0x5e, 0x34, 0x33, 0xf7, 0x7f, 0x0c, 0x3a, 0x3d, 0xc2,
+	0x90, 0xac, 0x77, 0x62, 0x0f, 0xb5, 0xf3, 0x86, 0x3e, 0x0c, 0x32, 0xc9, 0x54, 0xab, 0x27, 0x12,
+	0x3b, 0xa8, 0xac, 0xc1, 0xaa, 0xb8, 0xca, 0x1a, 0x81, 0xb0, 0x89, 0x14, 0x6e, 0xf6, 0x48, 0xc8,
+	0x8b, 0x9c, 0x31, 0x37, 0x83, 0x31, 0x81, 0x62, 0xc4, 0xd5, 0xd8, 0x4c, 0x28, 0x04, 0xac, 0xae,
+	0x3e, 0x24, 0x5c, 0x4b, 0xa6, 0xb6, 0xba, 0xe4, 0xec, 0xd2, 0x70, 0x8a, 0x58, 0x4b, 0xae, 0x5a,
+	0x5d, 0x72, 0x7e, 0xc3, 0x99, 0x86, 0x4b, 0x3a, 0xe3, 0x46, 0x32, 0xb5, 0xd6, 0x13, 0xe5, 0x9b,
+	0x6f, 0x14, 0xa2, 0xf5, 0x0e, 0x1b, 0xc9, 0x14, 0xd7, 0x33, 0x8a, 0x67, 0xe0, 0x29, 0x5d, 0xb0,
+	0x2d, 0x36, 0x47, 0xd1, 0x43, 0x67, 0x68, 0xfc, 0x26, 0x05, 0x32, 0x08, 0x92, 0xa9, 0x46, 0x2f,
+	0x5c, 0xff, 0x09, 0xdd, 0xbb, 0x73, 0xfe, 0xc7, 0x1d, 0xe9, 0x9b, 0x5c, 0x12, 0x6f, 0xb0, 0x0d,
+	0x0f, 0x5b, 0x44, 0x64, 0x92, 0xab, 0xa7, 0x97, 0xfd, 0xe1, 0x3e, 0xdf, 0xe3, 0x54, 0x7a, 0x79,
+	0xf8, 0xab, 0x2e, 0xdb, 0xbe, 0xfe, 0x0f, 0x00, 0x94, 0x71, 0x1b, 0x58, 0x6d, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,143 @@
+package registry
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zllai/gossip"
+)
+
+func TestRegistry(t *testing.T) {
+	r, err := New(gossip.New(gossip.NewNodeId("127.0.0.1:1"), "test topic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	changes := make(chan []Instance, 16)
+	stop := r.Watch("db", func(instances []Instance) {
+		changes <- instances
+	})
+	defer stop()
+
+	if err := r.Register(Service{Name: "db", Address: "10.0.0.1", Port: 5432, Tags: []string{"primary"}}); err != nil {
+		t.Fatal(err)
+	}
+	if instances := r.Lookup("db", "primary"); len(instances) != 1 || instances[0].Port != 5432 || instances[0].Id != "db" {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+	if instances := r.Lookup("db", "replica"); len(instances) != 0 {
+		t.Errorf("unexpected instances %v", instances)
+	}
+	if instances := <-changes; len(instances) != 1 {
+		t.Errorf("unexpected change %v", instances)
+	}
+
+	// unhealthy instances are not returned
+	r.SetHealth("db", HealthCritical)
+	if instances := r.Lookup("db"); len(instances) != 0 {
+		t.Errorf("unexpected instances %v", instances)
+	}
+	if instances := <-changes; len(instances) != 0 {
+		t.Errorf("unexpected change %v", instances)
+	}
+	r.SetHealth("db", HealthPassing)
+	<-changes
+
+	// registrations of other nodes expire unless refreshed
+	remote := &Registration{NodeId: "127.0.0.1:2", Id: "db", Service: "db", Version: time.Now().UnixNano(), Ttl: int64(100 * time.Millisecond)}
+	data, _ := proto.Marshal(&Announcement{Registrations: []*Registration{remote}})
+	r.receive(gossip.NewNodeId("127.0.0.1:2"), data)
+	if instances := <-changes; len(instances) != 2 {
+		t.Errorf("unexpected change %v", instances)
+	}
+	time.Sleep(150 * time.Millisecond)
+	r.expire()
+	if instances := <-changes; len(instances) != 1 {
+		t.Errorf("unexpected change %v", instances)
+	}
+	// and expired ones are not taken again
+	r.receive(gossip.NewNodeId("127.0.0.1:2"), data)
+	if instances := r.Lookup("db"); len(instances) != 1 {
+		t.Errorf("unexpected instances %v", instances)
+	}
+
+	// synced registrations are only taken from their node, and only within
+	// bounds
+	other := gossip.NewNodeId("127.0.0.1:3")
+	for _, reg := range []*Registration{
+		{NodeId: "127.0.0.1:2", Id: "db", Service: "db", Version: time.Now().UnixNano(), Ttl: int64(time.Minute)},
+		{NodeId: other.String(), Id: "db", Service: "db", Version: time.Now().Add(time.Hour).UnixNano(), Ttl: int64(time.Minute)},
+		{NodeId: other.String(), Id: "db", Service: "db", Version: time.Now().UnixNano(), Ttl: math.MaxInt64},
+	} {
+		value, _ := proto.Marshal(reg)
+		r.MergeFrom(other, key(reg), value)
+	}
+	if instances := r.Lookup("db"); len(instances) != 1 {
+		t.Errorf("unexpected instances %v", instances)
+	}
+	if err := r.Register(Service{Name: "cache", TTL: 2 * maxTTL}); err == nil {
+		t.Error("registered a TTL beyond the maximum")
+	}
+
+	r.Deregister("db")
+	if instances := <-changes; len(instances) != 0 {
+		t.Errorf("unexpected change %v", instances)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	bootNode := gossip.New(gossip.NewNodeId("127.0.0.1:0"), "test topic")
+	if err := bootNode.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bootNode.Close()
+	a, err := New(bootNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	a.Register(Service{Name: "web", Port: 80})
+
+	// a new node learns existing registrations by syncing
+	node := gossip.New(gossip.NewNodeId("127.0.0.1:0"), "test topic")
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	b, err := New(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	node.Join([]gossip.NodeId{bootNode.NodeId()})
+	waitFor(t, func() bool {
+		return node.PeerSupports(bootNode.NodeId(), gossip.FeatureMerkleSync) && bootNode.PeerSupports(node.NodeId(), gossip.FeatureChannels)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := node.SyncWith(ctx, bootNode.NodeId(), channel); err != nil {
+		t.Fatal(err)
+	}
+	if instances := b.Lookup("web"); len(instances) != 1 || instances[0].NodeId != bootNode.NodeId() {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+
+	// and new registrations by gossip
+	b.Register(Service{Name: "web", Port: 8080})
+	waitFor(t, func() bool {
+		return len(a.Lookup("web")) == 2
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
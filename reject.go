@@ -0,0 +1,39 @@
+package gossip
+
+import (
+	"log"
+	"strings"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const topicMismatchLimit = 3
+
+const ReasonTopicMismatch = "topic does not match"
+
+// rejected handles a peer refusing our messages. A peer that keeps telling us
+// it serves another topic is misconfigured and gets banned.
+func (node *Node) rejected(nodeId NodeId, reason string) {
+	if reason == ReasonRateLimited {
+		return
+	}
+	if reason != ReasonTopicMismatch {
+		log.Printf("[gossip] Node %s rejected data: %s", nodeId.String(), reason)
+		return
+	}
+	if node.neighbors.AddMismatch(nodeId) < topicMismatchLimit {
+		node.score(nodeId, ScoreTopicMismatch)
+		return
+	}
+	log.Printf("[gossip] Node %s is misconfigured: %s", nodeId.String(), reason)
+	node.emitPeer(EventPeerMisconfigured, nodeId)
+	node.ban(nodeId)
+}
+
+// isTopicMismatch reports whether err is a peer telling us it serves another
+// topic, rather than failing some other precondition.
+func isTopicMismatch(err error) bool {
+	s := status.Convert(err)
+	return s.Code() == codes.FailedPrecondition && strings.HasSuffix(s.Message(), ReasonTopicMismatch)
+}
@@ -0,0 +1,104 @@
+package gossip
+
+import (
+	"context"
+	"testing"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSendDataStatus(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	sender := New(NewNodeId("127.0.0.1:9111"), "test topic")
+	data, err := sender.newGossipData([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := node.SendData(context.Background(), data)
+	if err != nil || res.Status != SendDataRes_NEW {
+		t.Fatalf("unexpected result %v, %v", res, err)
+	}
+	<-node.GetMsgChan()
+	res, err = node.SendData(context.Background(), data)
+	if err != nil || res.Status != SendDataRes_DUPLICATE {
+		t.Fatalf("expected duplicate, got %v, %v", res, err)
+	}
+
+	other := *data
+	other.Topic = "other topic"
+	other.Nonce++
+	res, err = node.SendData(context.Background(), &other)
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonTopicMismatch {
+		t.Fatalf("expected topic mismatch, got %v, %v", res, err)
+	}
+
+	node.SetMaxMessageSize(4)
+	big, _ := sender.newGossipData([]byte("hello"))
+	res, err = node.SendData(context.Background(), big)
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason == "" {
+		t.Fatalf("expected oversized message to be rejected, got %v, %v", res, err)
+	}
+}
+
+func TestTopicMismatchEviction(t *testing.T) {
+	peer := New(NewNodeId("127.0.0.1:0"), "other topic")
+	if err := peer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	node.neighbors.Update(peer.NodeId())
+	conn, err := node.neighbors.GetConn(peer.NodeId())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewGossipClient(conn)
+
+	for i := 0; i < topicMismatchLimit; i++ {
+		data, _ := node.newGossipData([]byte("hello"))
+		if node.sendData(client, peer.NodeId(), data) {
+			t.Fatal("peer on another topic took the message")
+		}
+	}
+	if !node.neighbors.IsBlackListed(peer.NodeId()) {
+		t.Fatal("misconfigured peer not banned")
+	}
+	if _, err := node.neighbors.GetConn(peer.NodeId()); err == nil {
+		t.Error("misconfigured peer still a neighbor")
+	}
+	for {
+		select {
+		case event := <-node.GetEventChan():
+			if event.Type != EventPeerMisconfigured {
+				continue
+			}
+			if event.NodeId != peer.NodeId() {
+				t.Errorf("unexpected event %+v", event)
+			}
+			return
+		default:
+			t.Fatal("no misconfigured peer event")
+		}
+	}
+}
+
+func TestIsTopicMismatch(t *testing.T) {
+	cases := []struct {
+		err      error
+		mismatch bool
+	}{
+		{status.Errorf(codes.FailedPrecondition, "[From a] %s", ReasonTopicMismatch), true},
+		{status.Errorf(codes.FailedPrecondition, "[From a] protocol version 9 is not supported"), false},
+		{status.Errorf(codes.Unavailable, "[From a] %s", ReasonTopicMismatch), false},
+		{status.Errorf(codes.Unavailable, "connection refused"), false},
+	}
+	for _, c := range cases {
+		if isTopicMismatch(c.err) != c.mismatch {
+			t.Errorf("isTopicMismatch(%v) != %v", c.err, c.mismatch)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package gossip
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ReasonBanned refuses calls from banned nodes and messages they published.
+const ReasonBanned = "banned"
+
+type ScoreEvent int
+
+const (
+	ScoreInvalidMessage ScoreEvent = iota
+	ScoreDuplicate
+	ScoreRPCFailure
+	ScoreTopicMismatch
+	ScoreFirstDelivery
+	ScoreRateLimited
+)
+
+type ScoreParams struct {
+	InvalidMessage float64
+	RPCFailure     float64
+	TopicMismatch  float64
+	FirstDelivery  float64
+	RateLimited    float64
+	// Duplicate is charged for every duplicate beyond DuplicateAllowance
+	// within one DecayInterval, since some duplicates are inherent to gossip.
+	Duplicate          float64
+	DuplicateAllowance int
+	// Scores are multiplied by Decay once per DecayInterval and capped at
+	// MaxScore, so that old behavior is forgotten and good behavior cannot
+	// be banked.
+	Decay         float64
+	DecayInterval time.Duration
+	MaxScore      float64
+	// Peers below GossipThreshold are not sent gossip, peers below
+	// BanThreshold are evicted and banned for BanDuration.
+	GossipThreshold float64
+	BanThreshold    float64
+	BanDuration     time.Duration
+}
+
+func DefaultScoreParams() ScoreParams {
+	return ScoreParams{
+		InvalidMessage:     -10,
+		RPCFailure:         -1,
+		TopicMismatch:      -20,
+		FirstDelivery:      1,
+		RateLimited:        -1,
+		Duplicate:          -0.5,
+		DuplicateAllowance: 1024,
+		Decay:              0.9,
+		DecayInterval:      time.Minute,
+		MaxScore:           100,
+		GossipThreshold:    -20,
+		BanThreshold:       -50,
+		BanDuration:        10 * time.Minute,
+	}
+}
+
+type PeerStats struct {
+	Score           float64
+	InvalidMessages int
+	Duplicates      int
+	RPCFailures     int
+	TopicMismatches int
+	FirstDeliveries int
+	RateLimited     int
+}
+
+type peerScore struct {
+	stats            PeerStats
+	windowDuplicates int
+	lastDecay        time.Time
+}
+
+type PeerScores struct {
+	params ScoreParams
+	scores map[NodeId]*peerScore
+	lock   *sync.Mutex
+}
+
+func NewPeerScores(params ScoreParams) *PeerScores {
+	return &PeerScores{
+		params: params,
+		scores: make(map[NodeId]*peerScore),
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (ps *PeerScores) SetParams(params ScoreParams) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	ps.params = params
+}
+
+func (ps *PeerScores) Params() ScoreParams {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return ps.params
+}
+
+// get returns the score of nodeId with decay applied up to now.
+func (ps *PeerScores) get(nodeId NodeId) *peerScore {
+	current := time.Now()
+	score, ok := ps.scores[nodeId]
+	if !ok {
+		score = &peerScore{lastDecay: current}
+		ps.scores[nodeId] = score
+		return score
+	}
+	if ps.params.DecayInterval <= 0 {
+		return score
+	}
+	intervals := int(current.Sub(score.lastDecay) / ps.params.DecayInterval)
+	if intervals > 0 {
+		score.stats.Score *= math.Pow(ps.params.Decay, float64(intervals))
+		score.windowDuplicates = 0
+		score.lastDecay = score.lastDecay.Add(time.Duration(intervals) * ps.params.DecayInterval)
+	}
+	return score
+}
+
+// Record accounts an event to nodeId and returns its new score.
+func (ps *PeerScores) Record(nodeId NodeId, event ScoreEvent) float64 {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	score := ps.get(nodeId)
+	switch event {
+	case ScoreInvalidMessage:
+		score.stats.InvalidMessages++
+		score.stats.Score += ps.params.InvalidMessage
+	case ScoreDuplicate:
+		score.stats.Duplicates++
+		score.windowDuplicates++
+		if score.windowDuplicates > ps.params.DuplicateAllowance {
+			score.stats.Score += ps.params.Duplicate
+		}
+	case ScoreRPCFailure:
+		score.stats.RPCFailures++
+		score.stats.Score += ps.params.RPCFailure
+	case ScoreTopicMismatch:
+		score.stats.TopicMismatches++
+		score.stats.Score += ps.params.TopicMismatch
+	case ScoreFirstDelivery:
+		score.stats.FirstDeliveries++
+		score.stats.Score += ps.params.FirstDelivery
+	case ScoreRateLimited:
+		score.stats.RateLimited++
+		score.stats.Score += ps.params.RateLimited
+	}
+	if score.stats.Score > ps.params.MaxScore {
+		score.stats.Score = ps.params.MaxScore
+	}
+	return score.stats.Score
+}
+
+func (ps *PeerScores) Get(nodeId NodeId) float64 {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if _, ok := ps.scores[nodeId]; !ok {
+		return 0
+	}
+	return ps.get(nodeId).stats.Score
+}
+
+func (ps *PeerScores) Stats(nodeId NodeId) PeerStats {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if _, ok := ps.scores[nodeId]; !ok {
+		return PeerStats{}
+	}
+	return ps.get(nodeId).stats
+}
+
+func (ps *PeerScores) Remove(nodeId NodeId) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	delete(ps.scores, nodeId)
+}
+
+func (node *Node) SetScoreParams(params ScoreParams) {
+	node.scores.SetParams(params)
+}
+
+func (node *Node) PeerScore(nodeId NodeId) float64 {
+	return node.scores.Get(nodeId)
+}
+
+func (node *Node) PeerStats(nodeId NodeId) PeerStats {
+	return node.scores.Stats(nodeId)
+}
+
+// score records an event for nodeId and bans it once its score falls below
+// the ban threshold.
+func (node *Node) score(nodeId NodeId, event ScoreEvent) {
+	if node.scores.Record(nodeId, event) >= node.scores.Params().BanThreshold {
+		return
+	}
+	node.ban(nodeId)
+}
+
+// banned reports whether any of nodeIds is banned or blacklisted.
+func (node *Node) banned(nodeIds ...NodeId) bool {
+	for _, nodeId := range nodeIds {
+		if node.neighbors.IsBlackListed(nodeId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (node *Node) ban(nodeId NodeId) {
+	duration := node.scores.Params().BanDuration
+	log.Printf("[gossip] Banning node %s for %s", nodeId.String(), duration.String())
+	node.neighbors.BanFor(nodeId, duration)
+	node.scores.Remove(nodeId)
+	node.emitPeer(EventPeerBanned, nodeId)
+}
+
+// gossipable reports whether nodeId scores high enough to be sent gossip.
+func (node *Node) gossipable(nodeId NodeId) bool {
+	return node.scores.Get(nodeId) >= node.scores.Params().GossipThreshold
+}
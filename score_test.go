@@ -0,0 +1,126 @@
+package gossip
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestPeerScores(t *testing.T) {
+	params := DefaultScoreParams()
+	params.DuplicateAllowance = 1
+	params.DecayInterval = 50 * time.Millisecond
+	params.Decay = 0.5
+	scores := NewPeerScores(params)
+	nodeId := NewNodeId("127.0.0.1:9001")
+
+	scores.Record(nodeId, ScoreDuplicate)
+	if score := scores.Get(nodeId); score != 0 {
+		t.Errorf("duplicate within allowance scored %f", score)
+	}
+	scores.Record(nodeId, ScoreDuplicate)
+	scores.Record(nodeId, ScoreInvalidMessage)
+	if score := scores.Get(nodeId); score != params.Duplicate+params.InvalidMessage {
+		t.Errorf("unexpected score %f", score)
+	}
+	stats := scores.Stats(nodeId)
+	if stats.Duplicates != 2 || stats.InvalidMessages != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	time.Sleep(params.DecayInterval + params.DecayInterval/4)
+	if score := scores.Get(nodeId); score != (params.Duplicate+params.InvalidMessage)*params.Decay {
+		t.Errorf("score did not decay: %f", score)
+	}
+
+	for i := 0; i < 1000; i++ {
+		scores.Record(nodeId, ScoreFirstDelivery)
+	}
+	if score := scores.Get(nodeId); score != params.MaxScore {
+		t.Errorf("score not capped: %f", score)
+	}
+}
+
+func TestBanExpiry(t *testing.T) {
+	nl := NewNeighborList(neighborListCap)
+	nodeId := NewNodeId("127.0.0.1:9001")
+	nl.Update(nodeId)
+	nl.BanFor(nodeId, 50*time.Millisecond)
+	if nl.Len() != 0 || nl.Update(nodeId) {
+		t.Error("banned node was kept")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !nl.Update(nodeId) {
+		t.Error("ban did not expire")
+	}
+	nl.Close()
+}
+
+func TestBannedRefused(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	sender := New(NewNodeId("127.0.0.1:9121"), "test topic")
+	relay := NewNodeId("127.0.0.1:9122")
+	node.ban(sender.NodeId())
+
+	data, _ := sender.newGossipData([]byte("hello"))
+	res, err := node.SendData(context.Background(), data)
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonBanned {
+		t.Errorf("banned sender accepted: %v, %v", res, err)
+	}
+	// relaying does not launder messages of a banned origin
+	res, err = node.SendData(context.Background(), data.Relay(relay))
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonBanned {
+		t.Errorf("banned origin accepted: %v, %v", res, err)
+	}
+	if _, err := node.GetPeers(context.Background(), &NeighborReq{Topic: "test topic", NodeId: sender.NodeId().String(), MaxNum: 10}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("banned node got peers: %v", err)
+	}
+	if _, err := node.Handshake(context.Background(), sender.handshakeReq()); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("banned node shook hands: %v", err)
+	}
+	if _, ok := node.neighbors.GetPeerInfo(sender.NodeId()); ok || node.neighbors.Len() != 0 {
+		t.Error("banned node became a neighbor")
+	}
+}
+
+func TestSpoofedSender(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	node.SetRateLimits(RateLimits{PeerMessages: 1})
+	node.SetMaxMessageSize(4)
+	sender := New(NewNodeId("127.0.0.1:9141"), "test topic")
+	victim := NewNodeId("127.0.0.1:9142")
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.9"), Port: 4000}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+	// the invalid message is charged to the connection, not the victim
+	data, _ := sender.newGossipData([]byte("hello"))
+	res, err := node.SendData(ctx, data.Relay(victim))
+	if err != nil || res.Status != SendDataRes_REJECTED {
+		t.Fatalf("expected rejection, got %v, %v", res, err)
+	}
+	if stats := node.PeerStats(victim); stats.InvalidMessages != 0 {
+		t.Errorf("victim charged: %+v", stats)
+	}
+	if stats := node.PeerStats(NewNodeId(addr.String())); stats.InvalidMessages != 1 {
+		t.Errorf("caller not charged: %+v", stats)
+	}
+
+	// another claimed sender does not get a fresh bucket
+	data, _ = sender.newGossipData([]byte("hi"))
+	res, err = node.SendData(ctx, data.Relay(NewNodeId("127.0.0.1:9143")))
+	if err != nil || res.Status != SendDataRes_REJECTED || res.Reason != ReasonRateLimited {
+		t.Errorf("expected rate limited, got %v, %v", res, err)
+	}
+
+	// a sender on the host the call came from is believed
+	if from := caller(peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000}}), victim); from != victim {
+		t.Errorf("caller %s", from)
+	}
+}
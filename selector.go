@@ -0,0 +1,198 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const FeatureSelectors = "selectors"
+
+type selectorOp int
+
+const (
+	selectorEquals selectorOp = iota
+	selectorNotEquals
+	selectorIn
+	selectorNotIn
+	selectorExists
+	selectorNotExists
+)
+
+type selectorTerm struct {
+	key    string
+	op     selectorOp
+	values []string
+}
+
+func (term *selectorTerm) matches(metadata map[string]string) bool {
+	value, ok := metadata[term.key]
+	switch term.op {
+	case selectorEquals:
+		return ok && value == term.values[0]
+	case selectorNotEquals:
+		return !ok || value != term.values[0]
+	case selectorIn:
+		return ok && contains(term.values, value)
+	case selectorNotIn:
+		return !ok || !contains(term.values, value)
+	case selectorExists:
+		return ok
+	case selectorNotExists:
+		return !ok
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector matches node metadata. All of its terms have to match.
+type Selector struct {
+	expr  string
+	terms []*selectorTerm
+}
+
+// ParseSelector parses a comma separated list of terms over metadata keys:
+// "key=value", "key!=value", "key in (a,b)", "key notin (a,b)", "key" for
+// keys that are set and "!key" for keys that are not. The empty selector
+// matches every node.
+func ParseSelector(expr string) (*Selector, error) {
+	selector := &Selector{expr: expr}
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		// commas within parentheses separate values, not terms
+		end := len(rest)
+		depth := 0
+		for i, c := range rest {
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			} else if c == ',' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		term, err := parseSelectorTerm(strings.TrimSpace(rest[:end]))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("[gossip] Invalid selector %q: %s", expr, err.Error()))
+		}
+		selector.terms = append(selector.terms, term)
+		if end == len(rest) {
+			break
+		}
+		rest = strings.TrimSpace(rest[end+1:])
+		if rest == "" {
+			return nil, errors.New(fmt.Sprintf("[gossip] Invalid selector %q: trailing comma", expr))
+		}
+	}
+	return selector, nil
+}
+
+func parseSelectorTerm(term string) (*selectorTerm, error) {
+	if term == "" {
+		return nil, errors.New("empty term")
+	}
+	if i := strings.Index(term, "!="); i >= 0 {
+		return newSelectorTerm(term[:i], selectorNotEquals, []string{term[i+2:]})
+	}
+	if i := strings.Index(term, "="); i >= 0 {
+		return newSelectorTerm(term[:i], selectorEquals, []string{term[i+1:]})
+	}
+	if open := strings.Index(term, "("); open >= 0 {
+		if !strings.HasSuffix(term, ")") {
+			return nil, errors.New("unclosed parenthesis in " + term)
+		}
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 || fields[1] != "in" && fields[1] != "notin" {
+			return nil, errors.New("expected key in (...) or key notin (...) instead of " + term)
+		}
+		values := strings.Split(term[open+1:len(term)-1], ",")
+		op := selectorIn
+		if fields[1] == "notin" {
+			op = selectorNotIn
+		}
+		return newSelectorTerm(fields[0], op, values)
+	}
+	if strings.HasPrefix(term, "!") {
+		return newSelectorTerm(term[1:], selectorNotExists, nil)
+	}
+	return newSelectorTerm(term, selectorExists, nil)
+}
+
+func newSelectorTerm(key string, op selectorOp, values []string) (*selectorTerm, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.ContainsAny(key, " \t=!(),") {
+		return nil, errors.New(fmt.Sprintf("invalid key %q", key))
+	}
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return &selectorTerm{key, op, values}, nil
+}
+
+func (selector *Selector) Matches(metadata map[string]string) bool {
+	for _, term := range selector.terms {
+		if !term.matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func (selector *Selector) String() string {
+	return selector.expr
+}
+
+// GossipTo gossips data like Gossip, but only the nodes whose metadata
+// matches selector deliver it. The others relay it all the same.
+func (node *Node) GossipTo(selector string, data []byte) error {
+	if _, err := ParseSelector(selector); err != nil {
+		return err
+	}
+	gossipData, err := node.newGossipData(data)
+	if err != nil {
+		return err
+	}
+	gossipData.Selector = selector
+	node.publish(gossipData)
+	return nil
+}
+
+// selected reports whether the node should deliver data. Messages with a
+// selector that cannot be parsed are not delivered.
+func (node *Node) selected(data *GossipData) bool {
+	if data.Selector == "" {
+		return true
+	}
+	selector, err := ParseSelector(data.Selector)
+	return err == nil && selector.Matches(node.Metadata())
+}
+
+// samplePeers samples up to fanout neighbors to gossip data to. For targeted
+// messages, neighbors known to match come first.
+func (node *Node) samplePeers(fanout int, data *GossipData) []NodeId {
+	eligible := func(nodeId NodeId, info *PeerInfo) bool {
+		return node.gossipable(nodeId) && understands(info, data)
+	}
+	selector, err := ParseSelector(data.Selector)
+	if data.Selector == "" || err != nil {
+		return node.neighbors.SampleNodeIdWhere(fanout, eligible)
+	}
+	nodeIds := node.neighbors.SampleNodeIdWhere(fanout, func(nodeId NodeId, info *PeerInfo) bool {
+		return eligible(nodeId, info) && selector.Matches(info.Metadata)
+	})
+	if len(nodeIds) == fanout {
+		return nodeIds
+	}
+	return append(nodeIds, node.neighbors.SampleNodeIdWhere(fanout-len(nodeIds), func(nodeId NodeId, info *PeerInfo) bool {
+		return eligible(nodeId, info) && !selector.Matches(info.Metadata)
+	})...)
+}
@@ -0,0 +1,33 @@
+package gossip
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	metadata := map[string]string{"role": "indexer", "zone": "b"}
+	for expr, match := range map[string]bool{
+		"":                                true,
+		"role=indexer":                    true,
+		"role=indexer,zone in (a,b)":      true,
+		"role = indexer , zone in (a, b)": true,
+		"role=indexer,zone in (a,c)":      false,
+		"role!=indexer":                   false,
+		"zone notin (a,c)":                true,
+		"role":                            true,
+		"!role":                           false,
+		"!gpu,role":                       true,
+	} {
+		selector, err := ParseSelector(expr)
+		if err != nil {
+			t.Errorf("cannot parse %q: %s", expr, err)
+			continue
+		}
+		if selector.Matches(metadata) != match {
+			t.Errorf("%q matches %v: %v", expr, metadata, !match)
+		}
+	}
+	for _, expr := range []string{"role=indexer,", "zone in (a,b", "zone of (a)", "=a", ",role"} {
+		if _, err := ParseSelector(expr); err == nil {
+			t.Errorf("parsed invalid selector %q", expr)
+		}
+	}
+}
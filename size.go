@@ -0,0 +1,210 @@
+package gossip
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const FeatureSizeEstimation = "size-estimation"
+
+// sizeSamples is the number of random values every node contributes. The
+// relative error of the estimate is about 1/sqrt(sizeSamples-2).
+const sizeSamples = 64
+const sizeEpoch = 2 * time.Minute
+const sizeRoundInterval = time.Second
+const sizeTimeout = 5 * time.Second
+const minAdaptiveFanout = 3
+
+// bounds on what minima forged by a peer can do: the estimate does not grow
+// beyond maxEstimatedSize, nor direct messages beyond maxDirectTTL hops
+const maxEstimatedSize = 1 << 20
+const maxDirectTTL = 24
+
+// EstimatedSize estimates the number of nodes in the topic, including the
+// node itself. Nodes estimate it together with extrema propagation: each of
+// them draws exponentially distributed values, neighbors keep exchanging
+// the pointwise minimum, and the minima reveal how many nodes drew values.
+// The estimate starts over every epoch, so that it follows departures, and
+// it is never smaller than the neighborhood of the node.
+func (node *Node) EstimatedSize() int {
+	size := int(math.Floor(node.size.estimate() + 0.5))
+	if neighborhood := node.neighbors.Len() + 1; size < neighborhood {
+		size = neighborhood
+	}
+	return size
+}
+
+// SetAdaptiveFanout makes relays forward to about ln(n) + 3 peers and direct
+// messages travel up to about log2(n) hops, for the estimated size n,
+// instead of the fixed defaults.
+func (node *Node) SetAdaptiveFanout(enabled bool) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	node.adaptiveFanout = enabled
+}
+
+func (node *Node) isAdaptive() bool {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.adaptiveFanout
+}
+
+// relayFanout is how many peers a relay forwards a message to.
+func (node *Node) relayFanout() int {
+	if !node.isAdaptive() {
+		return gossipFanout
+	}
+	fanout := int(math.Ceil(math.Log(float64(node.EstimatedSize())))) + minAdaptiveFanout
+	if fanout > gossipFanout {
+		fanout = gossipFanout
+	}
+	return fanout
+}
+
+// directTTL is how many hops a direct message may be relayed.
+func (node *Node) directTTL() uint32 {
+	if !node.isAdaptive() {
+		return defaultDirectTTL
+	}
+	ttl := uint32(math.Ceil(math.Log2(float64(node.EstimatedSize())))) + 2
+	if ttl > maxDirectTTL {
+		ttl = maxDirectTTL
+	}
+	return ttl
+}
+
+func (node *Node) EstimateSize(ctx context.Context, req *SizeEstimate) (*SizeEstimate, error) {
+	if req.Topic != node.topic {
+		return nil, status.Errorf(codes.FailedPrecondition, "[From %s] %s", node.NodeId().String(), ReasonTopicMismatch)
+	}
+	if len(req.Mins) != sizeSamples {
+		return nil, status.Errorf(codes.InvalidArgument, "[From %s] %d samples instead of %d", node.NodeId().String(), len(req.Mins), sizeSamples)
+	}
+	node.size.merge(req.Epoch, req.Mins)
+	epoch, mins := node.size.current()
+	return &SizeEstimate{Topic: node.topic, Epoch: epoch, Mins: mins}, nil
+}
+
+// estimateSize exchanges minima with a random neighbor every round, until
+// the node is closed.
+func (node *Node) estimateSize() {
+	for node.sleep(sizeRoundInterval) {
+		nodeIds := node.neighbors.SampleNodeIdWhere(1, func(nodeId NodeId, info *PeerInfo) bool {
+			return info != nil && info.Supports(FeatureSizeEstimation)
+		})
+		if len(nodeIds) == 0 {
+			continue
+		}
+		conn, err := node.neighbors.GetConn(nodeIds[0])
+		if err != nil {
+			continue
+		}
+		epoch, mins := node.size.current()
+		ctx, cancel := context.WithTimeout(context.Background(), sizeTimeout)
+		res, err := NewGossipClient(conn).EstimateSize(ctx, &SizeEstimate{Topic: node.topic, Epoch: epoch, Mins: mins})
+		cancel()
+		if err != nil || len(res.Mins) != sizeSamples {
+			continue
+		}
+		node.size.merge(res.Epoch, res.Mins)
+	}
+}
+
+// sizeEstimator holds the minima of the current epoch. Epochs follow the
+// clock, so that nodes start over at about the same time.
+type sizeEstimator struct {
+	epoch    uint64
+	mins     []float64
+	previous float64
+	lock     *sync.Mutex
+}
+
+func newSizeEstimator() *sizeEstimator {
+	e := &sizeEstimator{lock: &sync.Mutex{}}
+	e.rotate(currentSizeEpoch())
+	return e
+}
+
+func currentSizeEpoch() uint64 {
+	return uint64(time.Now().UnixNano() / int64(sizeEpoch))
+}
+
+// rotate starts epoch with fresh values of the node. The caller holds the
+// lock.
+func (e *sizeEstimator) rotate(epoch uint64) {
+	if epoch == e.epoch+1 {
+		e.previous = e.size()
+	} else {
+		e.previous = 0
+	}
+	e.epoch = epoch
+	e.mins = make([]float64, sizeSamples)
+	for i := range e.mins {
+		e.mins[i] = rand.ExpFloat64()
+	}
+}
+
+// update moves to the current epoch, or to the next one if a peer is in it
+// already. Peers further ahead are ignored, so that they cannot skip epochs.
+// The caller holds the lock.
+func (e *sizeEstimator) update(epoch uint64) {
+	if current := currentSizeEpoch(); current > epoch || epoch > current+1 {
+		epoch = current
+	}
+	if epoch > e.epoch {
+		e.rotate(epoch)
+	}
+}
+
+func (e *sizeEstimator) current() (uint64, []float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.update(0)
+	return e.epoch, append([]float64(nil), e.mins...)
+}
+
+func (e *sizeEstimator) merge(epoch uint64, mins []float64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	// minima of past epochs include departed nodes
+	e.update(epoch)
+	if epoch != e.epoch {
+		return
+	}
+	for i := range mins {
+		if mins[i] >= 0 && mins[i] < e.mins[i] {
+			e.mins[i] = mins[i]
+		}
+	}
+}
+
+// size is the maximum likelihood estimate from the minima. The caller holds
+// the lock.
+func (e *sizeEstimator) size() float64 {
+	sum := 0.0
+	for i := range e.mins {
+		sum += e.mins[i]
+	}
+	if sum <= 0 {
+		return 0
+	}
+	return math.Min(float64(sizeSamples-1)/sum, maxEstimatedSize)
+}
+
+// estimate returns the larger of the estimates of this epoch and the last
+// one, since the estimate grows while an epoch goes on.
+func (e *sizeEstimator) estimate() float64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.update(0)
+	if size := e.size(); size > e.previous {
+		return size
+	}
+	return e.previous
+}
@@ -0,0 +1,67 @@
+package gossip
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSizeEstimator(t *testing.T) {
+	estimators := make([]*sizeEstimator, 200)
+	for i := range estimators {
+		estimators[i] = newSizeEstimator()
+	}
+	// random pairs exchange minima until all of them agree
+	for round := 0; round < 50; round++ {
+		for i := range estimators {
+			peer := estimators[rand.Intn(len(estimators))]
+			epoch, mins := estimators[i].current()
+			peer.merge(epoch, mins)
+			epoch, mins = peer.current()
+			estimators[i].merge(epoch, mins)
+		}
+	}
+	for i := range estimators {
+		if size := estimators[i].estimate(); size < 140 || size > 260 {
+			t.Fatalf("unexpected estimate %f for 200 nodes", size)
+		}
+	}
+
+	// minima of past epochs are ignored
+	e := estimators[0]
+	before := e.estimate()
+	epoch, mins := newSizeEstimator().current()
+	e.merge(epoch-1, mins)
+	if e.estimate() != before {
+		t.Error("merged minima of a past epoch")
+	}
+	// a later epoch starts over, but keeps the last estimate until it grows
+	e.merge(epoch+1, mins)
+	if e.epoch != epoch+1 || e.estimate() != before {
+		t.Errorf("unexpected epoch %d and estimate %f", e.epoch, e.estimate())
+	}
+}
+
+func TestSizeEstimatorBounds(t *testing.T) {
+	e := newSizeEstimator()
+	epoch, mins := e.current()
+	// epochs beyond the next are not adopted
+	e.merge(epoch+2, mins)
+	if e.epoch != epoch {
+		t.Errorf("adopted epoch %d from the future", e.epoch)
+	}
+
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	node.SetAdaptiveFanout(true)
+	tiny := make([]float64, sizeSamples)
+	for i := range tiny {
+		tiny[i] = 1e-300
+	}
+	epoch, _ = node.size.current()
+	node.size.merge(epoch, tiny)
+	if size := node.EstimatedSize(); size != maxEstimatedSize {
+		t.Errorf("estimate %d, expected it capped at %d", size, maxEstimatedSize)
+	}
+	if ttl := node.directTTL(); ttl > maxDirectTTL {
+		t.Errorf("direct TTL %d beyond %d", ttl, maxDirectTTL)
+	}
+}
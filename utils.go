@@ -5,6 +5,9 @@ import (
 	"crypto/md5"
 	"encoding/binary"
 	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 )
 
 func UInt64ToBytes(a uint64) []byte {
@@ -13,10 +16,54 @@ func UInt64ToBytes(a uint64) []byte {
 	return ret
 }
 func (gossipData *GossipData) Hash() string {
-	data := bytes.Join([][]byte{
+	parts := [][]byte{
 		UInt64ToBytes(gossipData.Nonce),
 		gossipData.Payload,
-	}, nil)
+	}
+	// identical payloads of different fragments are different messages
+	if fragment := gossipData.Fragment; fragment != nil {
+		parts = append(parts, fragment.Digest, UInt64ToBytes(uint64(fragment.Index)))
+	}
+	data := bytes.Join(parts, nil)
 	hashBytes := md5.Sum(data)
 	return hex.EncodeToString(hashBytes[:])
 }
+
+// From returns the neighbor that relayed the message. Peers that do not set
+// the sender relay only their own messages. SendData checks the sender
+// against the connection the message came over before anything uses it.
+func (gossipData *GossipData) From() NodeId {
+	if gossipData.Sender != "" {
+		return NewNodeId(gossipData.Sender)
+	}
+	return NewNodeId(gossipData.NodeId)
+}
+
+// Relay returns a copy of the message to be forwarded by sender.
+func (gossipData *GossipData) Relay(sender NodeId) *GossipData {
+	relayed := *gossipData
+	relayed.Sender = sender.String()
+	return &relayed
+}
+
+// writeFileAtomic writes to a temporary file and renames it over path, so a
+// crash never leaves a truncated file behind.
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package gossip
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultValidationTimeout = 5 * time.Second
+
+// at most that many validations run at once, so that validators that do
+// not return in time cannot pile up goroutines
+const maxPendingValidations = 256
+
+const ReasonValidationFailed = "validation failed"
+const ReasonValidationIgnored = "validation ignored"
+
+type ValidationResult int
+
+const (
+	ValidationAccept ValidationResult = iota
+	ValidationReject
+	ValidationIgnore
+)
+
+// Validator decides whether a message is delivered and forwarded. from is
+// the neighbor that relayed the message, data.NodeId is where it originated.
+// Rejected messages count against from, ignored ones are dropped silently.
+// Validators should return once ctx is done, since a validator that is still
+// running holds one of a limited number of slots.
+type Validator func(ctx context.Context, from NodeId, data *GossipData) ValidationResult
+
+type validators struct {
+	byTopic map[string][]Validator
+	timeout time.Duration
+	async   bool
+	slots   chan struct{}
+	lock    *sync.RWMutex
+}
+
+func newValidators() *validators {
+	return &validators{
+		byTopic: make(map[string][]Validator),
+		timeout: defaultValidationTimeout,
+		slots:   make(chan struct{}, maxPendingValidations),
+		lock:    &sync.RWMutex{},
+	}
+}
+
+// AddValidator runs validator on every message of topic received from a
+// neighbor, after the validators added before it. Messages published by the
+// node itself are not validated.
+func (node *Node) AddValidator(topic string, validator Validator) {
+	node.validators.lock.Lock()
+	defer node.validators.lock.Unlock()
+	node.validators.byTopic[topic] = append(node.validators.byTopic[topic], validator)
+}
+
+// SetValidationTimeout bounds how long the validators of one message may
+// take. Messages that time out are ignored, and so are messages that arrive
+// while too many validations are pending.
+func (node *Node) SetValidationTimeout(timeout time.Duration) {
+	node.validators.lock.Lock()
+	defer node.validators.lock.Unlock()
+	node.validators.timeout = timeout
+}
+
+// SetAsyncValidation makes SendData acknowledge messages before validating
+// them, so that slow validators do not hold up the relaying peer.
+func (node *Node) SetAsyncValidation(async bool) {
+	node.validators.lock.Lock()
+	defer node.validators.lock.Unlock()
+	node.validators.async = async
+}
+
+func (v *validators) isAsync() bool {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.async
+}
+
+func (v *validators) validate(from NodeId, data *GossipData) ValidationResult {
+	v.lock.RLock()
+	list := v.byTopic[data.Topic]
+	timeout := v.timeout
+	v.lock.RUnlock()
+	if len(list) == 0 {
+		return ValidationAccept
+	}
+
+	select {
+	case v.slots <- struct{}{}:
+	default:
+		log.Printf("[gossip] Too many pending validations, ignoring data from %s", from.String())
+		return ValidationIgnore
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result := make(chan ValidationResult, 1)
+	go func() {
+		defer func() {
+			<-v.slots
+		}()
+		for i := range list {
+			if ctx.Err() != nil {
+				result <- ValidationIgnore
+				return
+			}
+			if r := list[i](ctx, from, data); r != ValidationAccept {
+				result <- r
+				return
+			}
+		}
+		result <- ValidationAccept
+	}()
+	select {
+	case r := <-result:
+		return r
+	case <-ctx.Done():
+		log.Printf("[gossip] Validation of data from %s timed out", from.String())
+		return ValidationIgnore
+	}
+}
@@ -0,0 +1,58 @@
+package gossip
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidationIgnored(t *testing.T) {
+	node := New(NewNodeId("127.0.0.1:0"), "test topic")
+	defer node.Close()
+	node.AddValidator("test topic", func(ctx context.Context, from NodeId, data *GossipData) ValidationResult {
+		return ValidationIgnore
+	})
+	sender := New(NewNodeId("127.0.0.1:9121"), "test topic")
+	data, _ := sender.newGossipData([]byte("hello"))
+
+	res, err := node.SendData(context.Background(), data)
+	if err != nil || res.Status != SendDataRes_IGNORED {
+		t.Fatalf("expected ignored, got %v, %v", res, err)
+	}
+	if score := node.PeerScore(sender.NodeId()); score != 0 {
+		t.Errorf("ignored message changed the sender's score to %f", score)
+	}
+}
+
+func TestValidationSlots(t *testing.T) {
+	v := newValidators()
+	v.timeout = time.Millisecond
+	release := make(chan struct{})
+	var calls int32
+	// a validator that does not watch its context
+	v.byTopic["test topic"] = []Validator{func(ctx context.Context, from NodeId, data *GossipData) ValidationResult {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return ValidationAccept
+	}}
+	data := &GossipData{Topic: "test topic"}
+	for i := 0; i < maxPendingValidations+10; i++ {
+		if r := v.validate(NewNodeId("peer"), data); r != ValidationIgnore {
+			t.Fatalf("expected timed out validation to be ignored, got %d", r)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != maxPendingValidations {
+		t.Fatalf("%d validations running, expected at most %d", n, maxPendingValidations)
+	}
+
+	close(release)
+	v.timeout = time.Second
+	deadline := time.Now().Add(5 * time.Second)
+	for v.validate(NewNodeId("peer"), data) != ValidationAccept {
+		if time.Now().After(deadline) {
+			t.Fatal("validation slots not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}